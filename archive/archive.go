@@ -0,0 +1,121 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+
+	"github.com/pulpfree/gsales-fs-export/config"
+	"github.com/pulpfree/gsales-fs-export/model"
+)
+
+// Archive file names
+const (
+	SourceFile   = "source.jsonl.gz"
+	ExportedFile = "exported.jsonl.gz"
+	ResultFile   = "result.jsonl.gz"
+)
+
+// Store interface is implemented by the S3 and filesystem backends
+// Put must not overwrite an existing key
+type Store interface {
+	Put(key string, body []byte) error
+	Location(prefix string) string
+}
+
+// Batch struct holds everything written for a completed export
+// Source and Exported are slices, each element is written as a json line
+type Batch struct {
+	Exported interface{}
+	Result   *model.DnImportRes
+	Source   interface{}
+}
+
+// Archiver struct
+type Archiver struct {
+	stage string
+	store Store
+}
+
+// New function
+func New(store Store, stage config.StageEnvironment) *Archiver {
+	return &Archiver{stage: string(stage), store: store}
+}
+
+// FromConfig function returns an archiver using S3 when S3Bucket is set,
+// otherwise the filesystem when ArchiveDir is set. Returns nil when neither is set
+func FromConfig(cfg *config.Config) (*Archiver, error) {
+
+	if cfg.S3Bucket != "" {
+		store, err := NewS3Store(cfg.S3Bucket, cfg.AWSRegion)
+		if err != nil {
+			return nil, err
+		}
+		return New(store, cfg.GetStageEnv()), nil
+	}
+	if cfg.ArchiveDir != "" {
+		return New(&FileStore{Dir: cfg.ArchiveDir}, cfg.GetStageEnv()), nil
+	}
+
+	return nil, nil
+}
+
+// Prefix method returns the <stage>/<type>/<importTS> key prefix for a batch
+func (a *Archiver) Prefix(res *model.DnImportRes) string {
+	return path.Join(a.stage, res.ImportType, fmt.Sprintf("%d", res.ImportTS))
+}
+
+// Write method writes the gzipped json lines files for the batch, returning the location
+func (a *Archiver) Write(b *Batch) (location string, err error) {
+
+	prefix := a.Prefix(b.Result)
+	files := []struct {
+		name    string
+		records interface{}
+	}{
+		{SourceFile, b.Source},
+		{ExportedFile, b.Exported},
+		{ResultFile, b.Result},
+	}
+
+	for _, f := range files {
+		body, err := encodeLines(f.records)
+		if err != nil {
+			return location, err
+		}
+		if err = a.store.Put(path.Join(prefix, f.name), body); err != nil {
+			return location, err
+		}
+	}
+
+	return a.store.Location(prefix), err
+}
+
+// encodeLines gzips records as json lines, a slice is written a line per element
+func encodeLines(records interface{}) ([]byte, error) {
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(zw)
+
+	v := reflect.ValueOf(records)
+	if v.Kind() == reflect.Slice {
+		for i := 0; i < v.Len(); i++ {
+			if err := enc.Encode(v.Index(i).Interface()); err != nil {
+				return nil, err
+			}
+		}
+	} else if records != nil {
+		if err := enc.Encode(records); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
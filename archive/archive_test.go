@@ -0,0 +1,115 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/pulpfree/gsales-fs-export/config"
+	"github.com/pulpfree/gsales-fs-export/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeS3 struct {
+	s3iface.S3API
+	objects map[string][]byte
+}
+
+// PutObjectWithContext method fails a conditional put on an existing key as S3 does
+func (f *fakeS3) PutObjectWithContext(ctx aws.Context, in *s3.PutObjectInput, opts ...request.Option) (*s3.PutObjectOutput, error) {
+	r := &request.Request{HTTPRequest: &http.Request{Header: http.Header{}}}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	key := *in.Bucket + "/" + *in.Key
+	if _, ok := f.objects[key]; ok && r.HTTPRequest.Header.Get("If-None-Match") == "*" {
+		return nil, awserr.NewRequestFailure(awserr.New("PreconditionFailed", "At least one of the pre-conditions you specified did not hold", nil), http.StatusPreconditionFailed, "")
+	}
+	body, _ := ioutil.ReadAll(in.Body)
+	f.objects[key] = body
+	return &s3.PutObjectOutput{}, nil
+}
+
+func testBatch() *Batch {
+	return &Batch{
+		Exported: []*model.FuelSalesExport{{RecordDate: 20230601}, {RecordDate: 20230602}},
+		Result:   &model.DnImportRes{ImportTS: 1685836800, ImportType: "fuel", RecordQuantity: 2},
+		Source:   []model.StationSales{{Fuel1: 1200.5}},
+	}
+}
+
+func readLines(t *testing.T, body []byte) []string {
+	zr, err := gzip.NewReader(bytes.NewReader(body))
+	require.NoError(t, err)
+	var lines []string
+	sc := bufio.NewScanner(zr)
+	for sc.Scan() {
+		lines = append(lines, sc.Text())
+	}
+	return lines
+}
+
+// TestFileStore function
+func TestFileStore(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "archive")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	a := New(&FileStore{Dir: dir}, config.TestEnv)
+	loc, err := a.Write(testBatch())
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "test", "fuel", "1685836800")+string(filepath.Separator), loc)
+
+	body, err := ioutil.ReadFile(filepath.Join(loc, ExportedFile))
+	require.NoError(t, err)
+	lines := readLines(t, body)
+	assert.Len(t, lines, 2)
+
+	body, err = ioutil.ReadFile(filepath.Join(loc, ResultFile))
+	require.NoError(t, err)
+	lines = readLines(t, body)
+	require.Len(t, lines, 1)
+	res := &model.DnImportRes{}
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), res))
+	assert.Equal(t, 2, res.RecordQuantity)
+
+	// Archived batches are immutable
+	_, err = a.Write(testBatch())
+	assert.True(t, os.IsExist(err))
+}
+
+// TestS3Store function
+func TestS3Store(t *testing.T) {
+
+	svc := &fakeS3{objects: make(map[string][]byte)}
+	a := New(&S3Store{Bucket: "archive-bucket", svc: svc}, config.TestEnv)
+
+	loc, err := a.Write(testBatch())
+	require.NoError(t, err)
+	assert.Equal(t, "s3://archive-bucket/test/fuel/1685836800/", loc)
+	assert.Len(t, svc.objects, 3)
+	assert.Len(t, readLines(t, svc.objects["archive-bucket/test/fuel/1685836800/"+SourceFile]), 1)
+
+	// a second put on the same key fails rather than replacing the object
+	store := &S3Store{Bucket: "archive-bucket", svc: svc}
+	err = store.Put("test/fuel/1685836800/"+SourceFile, []byte("replaced"))
+	assert.True(t, os.IsExist(err))
+	assert.Len(t, readLines(t, svc.objects["archive-bucket/test/fuel/1685836800/"+SourceFile]), 1)
+
+	_, err = a.Write(testBatch())
+	assert.True(t, os.IsExist(err))
+}
@@ -0,0 +1,35 @@
+package archive
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// FileStore struct is a local filesystem stand-in for S3
+type FileStore struct {
+	Dir string
+}
+
+// Put method writes the file, failing if it already exists
+func (s *FileStore) Put(key string, body []byte) error {
+
+	fp := filepath.Join(s.Dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(fp), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(fp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0444)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(body); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Location method
+func (s *FileStore) Location(prefix string) string {
+	return filepath.Join(s.Dir, filepath.FromSlash(prefix)) + string(filepath.Separator)
+}
@@ -0,0 +1,58 @@
+package archive
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// S3Store struct
+type S3Store struct {
+	Bucket string
+	svc    s3iface.S3API
+}
+
+// NewS3Store function
+func NewS3Store(bucket, region string) (*S3Store, error) {
+
+	sess, err := session.NewSession(&aws.Config{
+		Region: aws.String(region),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &S3Store{Bucket: bucket, svc: s3.New(sess)}, nil
+}
+
+// Put method writes the object with If-None-Match so S3 refuses to replace an existing key
+// An existing key is returned as an os.ErrExist PathError, as FileStore does
+func (s *S3Store) Put(key string, body []byte) error {
+
+	_, err := s.svc.PutObjectWithContext(aws.BackgroundContext(), &s3.PutObjectInput{
+		Body:        bytes.NewReader(body),
+		Bucket:      aws.String(s.Bucket),
+		ContentType: aws.String("application/gzip"),
+		Key:         aws.String(key),
+	}, request.WithSetRequestHeaders(map[string]string{"If-None-Match": "*"}))
+
+	var reqErr awserr.RequestFailure
+	if errors.As(err, &reqErr) && reqErr.StatusCode() == http.StatusPreconditionFailed {
+		return &os.PathError{Op: "put", Path: fmt.Sprintf("s3://%s/%s", s.Bucket, key), Err: os.ErrExist}
+	}
+	return err
+}
+
+// Location method
+func (s *S3Store) Location(prefix string) string {
+	return fmt.Sprintf("s3://%s/%s/", s.Bucket, prefix)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/pulpfree/gsales-fs-export/config"
+)
+
+// runConfig handles the config subcommands, currently only print
+func runConfig(args []string) error {
+
+	if len(args) < 1 || args[0] != "print" {
+		return errors.New("Usage: fsexport config print [flags]")
+	}
+
+	fs := flag.NewFlagSet("config print", flag.ExitOnError)
+	defaults := fs.String("config", "config/defaults.yml", "path to the defaults file")
+	ssm := fs.Bool("ssm", true, "include ssm parameters, requires aws credentials")
+	fs.Parse(args[1:])
+
+	cfg := &config.Config{DefaultsFilePath: *defaults, SkipSSM: !*ssm}
+	if err := cfg.Load(); err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE\tFROM")
+	for _, s := range cfg.Settings() {
+		src, from := "-", "-"
+		if s.Origin != nil {
+			src, from = string(s.Origin.Source), s.Origin.Name
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", s.Key, s.Value, src, from)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+
+	"github.com/pulpfree/gsales-fs-export/export"
+	"github.com/pulpfree/gsales-fs-export/model"
+	"github.com/pulpfree/gsales-fs-export/render"
+	"github.com/pulpfree/gsales-fs-export/validators"
+)
+
+// runData fetches previously exported sales and writes them to a file or stdout
+func runData(args []string) error {
+
+	fs := flag.NewFlagSet("data", flag.ExitOnError)
+	defaults := fs.String("config", "config/defaults.yml", "path to the defaults file")
+	exportType := fs.String("type", "fuel", "export type: fuel or propane")
+	dateStart := fs.String("start", "", "start date (YYYY-MM-DD)")
+	dateEnd := fs.String("end", "", "end date (YYYY-MM-DD)")
+	format := fs.String("format", "json", "output format: json, csv or xlsx")
+	out := fs.String("out", "", "output file, defaults to stdout")
+	fs.Parse(args)
+
+	req, fmtType, err := validators.DataRequest(map[string]string{
+		"dateEnd":    *dateEnd,
+		"dateStart":  *dateStart,
+		"exportType": *exportType,
+		"format":     *format,
+	})
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(*defaults)
+	if err != nil {
+		return err
+	}
+
+	// workbooks always include both fuel and propane sheets
+	exporter := export.New(req, cfg)
+	var data *model.ExportData
+	if fmtType == model.FormatXLSX {
+		data, err = exporter.WorkbookData(context.Background())
+	} else {
+		data, err = exporter.Data(context.Background())
+	}
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	switch fmtType {
+	case model.FormatCSV:
+		return render.CSV(w, data)
+	case model.FormatXLSX:
+		return render.XLSX(w, data)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(data)
+}
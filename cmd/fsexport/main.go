@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pulpfree/gsales-fs-export/config"
+	"github.com/pulpfree/gsales-fs-export/logging"
+)
+
+// command struct
+type command struct {
+	run   func(args []string) error
+	usage string
+}
+
+var commands = map[string]command{
+	"config": {run: runConfig, usage: "print the loaded config with the source of each value, secrets redacted"},
+	"data":   {run: runData, usage: "download exported sales as json, csv or an xlsx workbook"},
+	"tables": {run: runTables, usage: "create the GDS dynamo tables for DynamoDB Local, or migrate FuelPrice keys and YearWeek values"},
+}
+
+func main() {
+
+	logging.Setup()
+
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+
+	if err := cmd.run(os.Args[2:]); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: fsexport <command> [flags]\n\nCommands:\n")
+	names := make([]string, 0, len(commands))
+	for nm := range commands {
+		names = append(names, nm)
+	}
+	sort.Strings(names)
+	for _, nm := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", nm, commands[nm].usage)
+	}
+}
+
+// loadConfig function
+func loadConfig(defaultsPath string) (*config.Config, error) {
+	cfg := &config.Config{DefaultsFilePath: defaultsPath}
+	err := cfg.Load()
+	return cfg, err
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+
+	"github.com/pulpfree/gsales-fs-export/model/dynamo"
+)
+
+const tablesUsage = "Usage: fsexport tables create|migrate-prices|fix-yearweek [flags]"
+
+// runTables handles the tables subcommands
+func runTables(args []string) error {
+
+	if len(args) < 1 {
+		return errors.New(tablesUsage)
+	}
+	switch args[0] {
+	case "create":
+		return runTablesCreate(args[1:])
+	case "migrate-prices":
+		return runMigratePrices(args[1:])
+	case "fix-yearweek":
+		return runFixYearWeek(args[1:])
+	}
+	return errors.New(tablesUsage)
+}
+
+// runTablesCreate creates the tables, refusing endpoints other than DynamoDB Local unless forced
+func runTablesCreate(args []string) error {
+
+	fs := flag.NewFlagSet("tables create", flag.ExitOnError)
+	defaults := fs.String("config", "config/defaults.yml", "path to the defaults file")
+	force := fs.Bool("force", false, "allow creating tables on an endpoint other than localhost")
+	fs.Parse(args)
+
+	cfg, err := loadConfig(*defaults)
+	if err != nil {
+		return err
+	}
+
+	if !*force && !isLocalEndpoint(cfg.Dynamo.Endpoint) {
+		return fmt.Errorf("Dynamo endpoint %q is not local, use -force to create tables there", cfg.Dynamo.Endpoint)
+	}
+
+	db, err := dynamo.NewDB(cfg.Dynamo)
+	if err != nil {
+		return err
+	}
+
+	created, err := db.CreateTables(context.Background())
+	for _, name := range created {
+		fmt.Printf("created %s\n", name)
+	}
+	return err
+}
+
+// runMigratePrices copies FuelPrice items keyed by date into the configured per grade FuelPrice table
+func runMigratePrices(args []string) error {
+
+	fs := flag.NewFlagSet("tables migrate-prices", flag.ExitOnError)
+	defaults := fs.String("config", "config/defaults.yml", "path to the defaults file")
+	dryRun := fs.Bool("dry-run", false, "count the items without writing them")
+	from := fs.String("from", "", "the FuelPrice table keyed by StationID and Date")
+	fs.Parse(args)
+
+	if *from == "" {
+		return errors.New("Usage: fsexport tables migrate-prices -from <table> [-config path] [-dry-run]")
+	}
+
+	cfg, err := loadConfig(*defaults)
+	if err != nil {
+		return err
+	}
+
+	db, err := dynamo.NewDB(cfg.Dynamo)
+	if err != nil {
+		return err
+	}
+
+	copied, err := db.MigrateFuelPrices(context.Background(), *from, *dryRun)
+	verb := "copied"
+	if *dryRun {
+		verb = "would copy"
+	}
+	fmt.Printf("%s %d items from %s to %s\n", verb, copied, *from, db.Table(dynamo.FuelPrice))
+	return err
+}
+
+// runFixYearWeek zero pads the YearWeek of items written before weeks were padded
+func runFixYearWeek(args []string) error {
+
+	fs := flag.NewFlagSet("tables fix-yearweek", flag.ExitOnError)
+	defaults := fs.String("config", "config/defaults.yml", "path to the defaults file")
+	dryRun := fs.Bool("dry-run", false, "count the items without writing them")
+	fs.Parse(args)
+
+	cfg, err := loadConfig(*defaults)
+	if err != nil {
+		return err
+	}
+
+	db, err := dynamo.NewDB(cfg.Dynamo)
+	if err != nil {
+		return err
+	}
+
+	fixed, err := db.MigrateYearWeeks(context.Background(), *dryRun)
+	verb := "fixed"
+	if *dryRun {
+		verb = "would fix"
+	}
+	fmt.Printf("%s the YearWeek of %d items\n", verb, fixed)
+	return err
+}
+
+// isLocalEndpoint function reports whether the endpoint is a DynamoDB Local address
+func isLocalEndpoint(endpoint string) bool {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return false
+	}
+	switch u.Hostname() {
+	case "localhost", "127.0.0.1", "::1", "dynamodb-local":
+		return true
+	}
+	return false
+}
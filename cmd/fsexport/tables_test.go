@@ -0,0 +1,14 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsLocalEndpoint(t *testing.T) {
+	assert.True(t, isLocalEndpoint("http://localhost:8000"))
+	assert.True(t, isLocalEndpoint("http://127.0.0.1:8000"))
+	assert.False(t, isLocalEndpoint("https://dynamodb.ca-central-1.amazonaws.com"))
+	assert.False(t, isLocalEndpoint(""))
+}
@@ -4,14 +4,16 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net/url"
 	"os"
 	"path"
-	"reflect"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+	log "github.com/sirupsen/logrus"
 	yaml "gopkg.in/yaml.v2"
 )
 
@@ -26,7 +28,10 @@ const (
 	ProdEnv  StageEnvironment = "prod"
 )
 
-const defaultFileName = "defaults.yml"
+const (
+	defaultFileName = "defaults.yml"
+	localLayer      = "local"
+)
 
 var (
 	defs = &defaults{}
@@ -45,20 +50,37 @@ func (c *Config) Load() (err error) {
 		return err
 	}
 
-	if err = c.setSSMParams(); err != nil {
-		return err
+	if !c.SkipSSM {
+		if err = c.setSSMParams(); err != nil {
+			return err
+		}
 	}
 
 	if err = c.setEnvVars(); err != nil {
 		return err
 	}
 
-	c.setDBConnectURL()
-	c.setFinal()
+	if err = validateRequired(fields(defs)); err != nil {
+		return err
+	}
+
+	if err = validateWebhookURL(defs.NotifyWebhookURL); err != nil {
+		return err
+	}
+
+	if err = c.setDBConnectURL(); err != nil {
+		return err
+	}
+	err = c.setFinal()
 
 	return err
 }
 
+// Settings method lists every config value with the source that supplied it, secrets are redacted
+func (c *Config) Settings() []*Setting {
+	return settings(fields(defs), c.origins)
+}
+
 // GetStageEnv method
 func (c *Config) GetStageEnv() StageEnvironment {
 	return c.Stage
@@ -70,6 +92,8 @@ func (c *Config) GetMongoConnectURL() string {
 }
 
 // this must be called first in c.Load
+// The defaults file is layered with defaults.<stage>.yml and then defaults.local.yml from the same directory,
+// each overriding the keys it sets. Only the first file is required.
 func (c *Config) setDefaults() (err error) {
 
 	if c.DefaultsFilePath == "" {
@@ -77,7 +101,39 @@ func (c *Config) setDefaults() (err error) {
 		c.DefaultsFilePath = path.Join(dir, defaultFileName)
 	}
 
-	file, err := ioutil.ReadFile(c.DefaultsFilePath)
+	defs = &defaults{}
+	c.origins = make(map[string]*Origin)
+
+	if err = c.loadDefaultsFile(c.DefaultsFilePath); err != nil {
+		return err
+	}
+
+	localPath := layerPath(c.DefaultsFilePath, localLayer)
+	stage, err := layerStage(localPath)
+	if err != nil {
+		return err
+	}
+
+	for _, fp := range []string{layerPath(c.DefaultsFilePath, string(stage)), localPath} {
+		err = c.loadDefaultsFile(fp)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		log.Debugf("Loaded defaults layer %s", fp)
+	}
+
+	err = c.validateStage()
+
+	return err
+}
+
+// loadDefaultsFile method merges a yaml file into the defaults and records it as the origin of each key it sets
+func (c *Config) loadDefaultsFile(filePath string) (err error) {
+
+	file, err := ioutil.ReadFile(filePath)
 	if err != nil {
 		return err
 	}
@@ -86,64 +142,103 @@ func (c *Config) setDefaults() (err error) {
 	if err != nil {
 		return err
 	}
-	err = c.validateStage()
+
+	doc := make(map[interface{}]interface{})
+	if err = yaml.Unmarshal([]byte(file), &doc); err != nil {
+		return err
+	}
+	fm := fieldMap(fields(defs))
+	for _, key := range fileKeys(doc, "") {
+		f, _, ok := lookupField(fm, key)
+		if !ok {
+			log.Warnf("Unknown key %s in %s", key, filePath)
+			continue
+		}
+		c.origins[f.key] = &Origin{Name: filePath, Source: SourceFile}
+	}
 
 	return err
 }
 
+// layerStage function returns the stage whose defaults layer is loaded
+// The Stage env var wins, then Stage in the local file, then the value already loaded
+func layerStage(localPath string) (StageEnvironment, error) {
+
+	stage := defs.Stage
+	if file, err := ioutil.ReadFile(localPath); err == nil {
+		local := struct {
+			Stage string `yaml:"Stage"`
+		}{}
+		if err = yaml.Unmarshal(file, &local); err != nil {
+			return "", err
+		}
+		if local.Stage != "" {
+			stage = local.Stage
+		}
+	}
+	for _, kv := range os.Environ() {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) == 2 && parts[1] != "" && normaliseKey(parts[0]) == "stage" {
+			stage = parts[1]
+		}
+	}
+
+	return parseStage(stage)
+}
+
+// layerPath function returns the name of a layer next to the defaults file, e.g. defaults.test.yml
+func layerPath(filePath, layer string) string {
+	ext := path.Ext(filePath)
+	return strings.TrimSuffix(filePath, ext) + "." + layer + ext
+}
+
 // validateStage method to validate Stage value
 func (c *Config) validateStage() (err error) {
 
-	validEnv := true
+	c.Stage, err = parseStage(defs.Stage)
+
+	return err
+}
+
+// parseStage function maps a stage name, including its long forms, to a StageEnvironment
+func parseStage(stage string) (StageEnvironment, error) {
 
-	switch defs.Stage {
-	case "dev":
-	case "development":
-		c.Stage = DevEnv
+	switch stage {
+	case "dev", "development":
+		return DevEnv, nil
 	case "stage":
-		c.Stage = StageEnv
+		return StageEnv, nil
 	case "test":
-		c.Stage = TestEnv
-	case "prod":
-		c.Stage = ProdEnv
-	case "production":
-		c.Stage = ProdEnv
-	default:
-		validEnv = false
-	}
-
-	if !validEnv {
-		return errors.New(fmt.Sprintf("Invalid StageEnvironment requested: %s", defs.Stage))
+		return TestEnv, nil
+	case "prod", "production":
+		return ProdEnv, nil
 	}
 
-	return err
+	return "", errors.New(fmt.Sprintf("Invalid StageEnvironment requested: %s", stage))
 }
 
 // sets any environment variables that match the default struct fields
+// nested fields are matched with an underscore separator, e.g. Dynamo_Region or DYNAMO__REGION
 func (c *Config) setEnvVars() (err error) {
 
-	vals := reflect.Indirect(reflect.ValueOf(defs))
-	for i := 0; i < vals.NumField(); i++ {
-		nm := vals.Type().Field(i).Name
-		if e := os.Getenv(nm); e != "" {
-			vals.Field(i).SetString(e)
-		}
-		// If field is Stage, validate and return error if required
-		if nm == "Stage" {
-			err = c.validateStage()
-			if err != nil {
-				return err
-			}
-		}
+	if c.origins == nil {
+		c.origins = make(map[string]*Origin)
+	}
+	if err = applyEnv(fieldMap(fields(defs)), os.Environ(), c.origins); err != nil {
+		return err
 	}
 
-	return err
+	// validate Stage and return error if required
+	return c.validateStage()
 }
 
+// setSSMParams method loads the parameters below /<stage>/<SsmPath>
+// When SsmParamsFile is set the parameters are read from that file instead, so AWS credentials are not needed
 func (c *Config) setSSMParams() (err error) {
 
-	s := []string{"", string(c.GetStageEnv()), defs.SsmPath}
-	paramPath := aws.String(strings.Join(s, "/"))
+	if defs.SsmParamsFile != "" {
+		return c.setFileParams(defs.SsmParamsFile)
+	}
 
 	sess, err := session.NewSession(&aws.Config{
 		Region: aws.String(defs.AWSRegion),
@@ -152,56 +247,147 @@ func (c *Config) setSSMParams() (err error) {
 		return err
 	}
 
-	svc := ssm.New(sess)
-	res, err := svc.GetParametersByPath(&ssm.GetParametersByPathInput{
-		Path:           paramPath,
-		WithDecryption: aws.Bool(true),
-	})
+	paramPath := c.ssmPath()
+	params, err := fetchSSMParams(ssm.New(sess), paramPath)
 	if err != nil {
 		return err
 	}
 
-	paramLen := len(res.Parameters)
-	if paramLen == 0 {
-		// err = fmt.Errorf("Error fetching ssm params, total number found: %d", paramLen)
-		return nil
+	return c.applyParams(paramPath, params, "")
+}
+
+// ssmPath method returns the parameter path, e.g. /prod/gdps-fs-import
+func (c *Config) ssmPath() string {
+	s := []string{"", string(c.GetStageEnv()), defs.SsmPath}
+	return strings.Join(s, "/")
+}
+
+// fetchSSMParams function returns every parameter below paramPath, including sub paths,
+// following NextToken until all pages are read
+func fetchSSMParams(svc ssmiface.SSMAPI, paramPath string) (params []*ssm.Parameter, err error) {
+
+	input := &ssm.GetParametersByPathInput{
+		Path:           aws.String(paramPath),
+		Recursive:      aws.Bool(true),
+		WithDecryption: aws.Bool(true),
+	}
+	for {
+		res, err := svc.GetParametersByPath(input)
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, res.Parameters...)
+		if res.NextToken == nil || *res.NextToken == "" {
+			return params, nil
+		}
+		input.NextToken = res.NextToken
 	}
+}
+
+// applyParams method sets the fields named by each parameter
+// Names below the path map to field keys, e.g. /prod/gdps-fs-import/Dynamo/Region sets Dynamo.Region
+// filePath is recorded as the origin when the parameters were read from a local file
+func (c *Config) applyParams(paramPath string, params []*ssm.Parameter, filePath string) (err error) {
 
-	// Get struct keys so we can test before attempting to set
-	t := reflect.ValueOf(defs).Elem()
-	for _, r := range res.Parameters {
-		paramName := strings.Split(*r.Name, "/")[3]
-		structKey := t.FieldByName(paramName)
-		if structKey.IsValid() {
-			structKey.Set(reflect.ValueOf(*r.Value))
+	if c.origins == nil {
+		c.origins = make(map[string]*Origin)
+	}
+	fm := fieldMap(fields(defs))
+	for _, r := range params {
+		name := strings.TrimPrefix(aws.StringValue(r.Name), paramPath+"/")
+		f, entry, ok := lookupField(fm, name)
+		if !ok {
+			log.Warnf("Unknown ssm parameter %s", aws.StringValue(r.Name))
+			continue
+		}
+		if err = setField(f, entry, aws.StringValue(r.Value)); err != nil {
+			return fmt.Errorf("Invalid value for %s from ssm %s: %s", f.key, aws.StringValue(r.Name), err)
+		}
+		if filePath != "" {
+			c.origins[f.key] = &Origin{Name: filePath, Source: SourceFile}
+			continue
 		}
+		c.origins[f.key] = &Origin{Name: aws.StringValue(r.Name), Source: SourceSSM}
 	}
 	return err
 }
 
-// Build a url used in mgo.Dial as described in: https://godoc.org/gopkg.in/mgo.v2#Dial
-func (c *Config) setDBConnectURL() *Config {
+// setFileParams method reads parameters from a local yaml file standing in for ssm
+// Keys are named as below the ssm path, either nested or as Dynamo/Region
+func (c *Config) setFileParams(filePath string) (err error) {
 
-	if c.GetStageEnv() != TestEnv {
-		c.setAWSConnectURL()
-		return c
+	file, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return err
 	}
 
-	c.MongoDBConnectURL = fmt.Sprintf("mongodb://%s/?readPreference=primary&ssl=false&directConnection=true", defs.MongoDBHost)
+	doc := make(map[interface{}]interface{})
+	if err = yaml.Unmarshal(file, &doc); err != nil {
+		return err
+	}
 
-	return c
+	paramPath := c.ssmPath()
+	var params []*ssm.Parameter
+	for key, value := range fileValues(doc, "") {
+		params = append(params, &ssm.Parameter{
+			Name:  aws.String(paramPath + "/" + key),
+			Value: aws.String(value),
+		})
+	}
+	return c.applyParams(paramPath, params, filePath)
 }
 
-func (c *Config) setAWSConnectURL() {
-	c.MongoDBConnectURL = fmt.Sprintf("mongodb+srv://%s/%s?authSource=%sexternal&authMechanism=MONGODB-AWS&retryWrites=true&w=majority", defs.MongoDBHost, defs.MongoDBName, "$")
+// setDBConnectURL method builds the mongo connection string from the configured auth mode
+// The test stage connects directly to a single host, other stages use an SRV record
+func (c *Config) setDBConnectURL() (err error) {
+
+	c.MongoDBConnectURL, err = mongoConnectURL(&mongoConn{
+		Auth:        mongoAuth(defs.MongoDBAuth, c.GetStageEnv()),
+		AuthSource:  defs.MongoDBAuthSource,
+		CAFile:      defs.MongoDBCAFile,
+		CertKeyFile: defs.MongoDBCertKeyFile,
+		Host:        defs.MongoDBHost,
+		Name:        defs.MongoDBName,
+		Password:    defs.MongoDBPassword,
+		SRV:         c.GetStageEnv() != TestEnv,
+		User:        defs.MongoDBUser,
+	})
+
+	return err
+}
+
+// validateWebhookURL function returns an error when a webhook is set to anything but an absolute https url
+// Summaries carry export results, so are only posted over https
+func validateWebhookURL(raw string) error {
+
+	if raw == "" {
+		return nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("Invalid NotifyWebhookURL, must be an https url: %s", raw)
+	}
+	return nil
 }
 
 // Copies required fields from the defaults to the Config struct
 func (c *Config) setFinal() (err error) {
 
 	c.AWSRegion = defs.AWSRegion
+	c.ArchiveDir = defs.ArchiveDir
 	c.Dynamo = defs.Dynamo
+	c.MailFrom = defs.MailFrom
+	c.MailTo = defs.MailTo
+	c.MongoDBBatchSize = defs.MongoDBBatchSize
 	c.MongoDBName = defs.MongoDBName
+	c.NotifyTimeout = defs.NotifyTimeout
+	c.NotifyTopicArn = defs.NotifyTopicArn
+	c.NotifyWebhookSecret = defs.NotifyWebhookSecret
+	c.NotifyWebhookURL = defs.NotifyWebhookURL
+	c.S3Bucket = defs.S3Bucket
+	c.SMTPAddr = defs.SMTPAddr
+	c.SMTPPassword = defs.SMTPPassword
+	c.SMTPUser = defs.SMTPUser
 
 	err = c.validateStage()
 
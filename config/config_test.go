@@ -0,0 +1,17 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateWebhookURL(t *testing.T) {
+
+	assert.NoError(t, validateWebhookURL(""))
+	assert.NoError(t, validateWebhookURL("https://hooks.example.com/export"))
+
+	for _, raw := range []string{"http://hooks.example.com/export", "hooks.example.com/export", "https://", "ftp://hooks.example.com"} {
+		assert.EqualError(t, validateWebhookURL(raw), "Invalid NotifyWebhookURL, must be an https url: "+raw)
+	}
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeLayers writes each named file into a new temp dir and returns the dir
+func writeLayers(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "config")
+	assert.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	for name, doc := range files {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(doc), 0600))
+	}
+	return dir
+}
+
+// setStage sets the Stage env var, or clears it when empty, for the duration of a test
+// The cleanup restores the value from before, unsetting Stage if it was not set
+func setStage(t *testing.T, stage string) {
+	saved, ok := os.LookupEnv("Stage")
+	if stage == "" {
+		os.Unsetenv("Stage")
+	} else {
+		os.Setenv("Stage", stage)
+	}
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv("Stage", saved)
+		} else {
+			os.Unsetenv("Stage")
+		}
+	})
+}
+
+const baseLayer = `AWSRegion: ca-central-1
+MongoDBHost: ""
+MongoDBName: sales
+SsmPath: fs
+Stage: prod
+Dynamo:
+  Region: ca-central-1
+  Endpoint: https://dynamodb.ca-central-1.amazonaws.com
+`
+
+func TestSetDefaultsLayers(t *testing.T) {
+
+	setStage(t, "")
+	withDefs(t, &defaults{})
+	dir := writeLayers(t, map[string]string{
+		"defaults.yml":       baseLayer,
+		"defaults.test.yml":  "MongoDBHost: 10.0.0.5\nMongoDBName: sales-test\nDynamo:\n  Endpoint: http://localhost:8000\n",
+		"defaults.prod.yml":  "MongoDBHost: prod.example.com\n",
+		"defaults.local.yml": "Stage: test\nMongoDBName: sales-local\n",
+	})
+
+	c := &Config{DefaultsFilePath: filepath.Join(dir, "defaults.yml")}
+	assert.NoError(t, c.setDefaults())
+	assert.Equal(t, TestEnv, c.Stage)
+	assert.Equal(t, "10.0.0.5", defs.MongoDBHost)
+	assert.Equal(t, "sales-local", defs.MongoDBName)
+	assert.Equal(t, "http://localhost:8000", defs.Dynamo.Endpoint)
+	assert.Equal(t, "ca-central-1", defs.Dynamo.Region)
+
+	assert.Equal(t, filepath.Join(dir, "defaults.yml"), c.origins["AWSRegion"].Name)
+	assert.Equal(t, filepath.Join(dir, "defaults.test.yml"), c.origins["MongoDBHost"].Name)
+	assert.Equal(t, filepath.Join(dir, "defaults.local.yml"), c.origins["MongoDBName"].Name)
+}
+
+func TestSetDefaultsStageFromEnv(t *testing.T) {
+
+	setStage(t, "")
+	withDefs(t, &defaults{})
+	dir := writeLayers(t, map[string]string{
+		"defaults.yml":      baseLayer,
+		"defaults.test.yml": "MongoDBHost: 10.0.0.5\n",
+	})
+
+	c := &Config{DefaultsFilePath: filepath.Join(dir, "defaults.yml")}
+	assert.NoError(t, c.setDefaults())
+	assert.Equal(t, ProdEnv, c.Stage)
+	assert.Equal(t, "", defs.MongoDBHost)
+
+	setStage(t, "test")
+	assert.NoError(t, c.setDefaults())
+	assert.Equal(t, "10.0.0.5", defs.MongoDBHost)
+}
+
+func TestSetDefaultsInvalidLayer(t *testing.T) {
+
+	setStage(t, "")
+	withDefs(t, &defaults{})
+	dir := writeLayers(t, map[string]string{
+		"defaults.yml":      baseLayer,
+		"defaults.prod.yml": "Dynamo: [",
+	})
+
+	c := &Config{DefaultsFilePath: filepath.Join(dir, "defaults.yml")}
+	assert.Error(t, c.setDefaults())
+
+	c = &Config{DefaultsFilePath: filepath.Join(dir, "missing.yml")}
+	assert.Error(t, c.setDefaults())
+}
+
+// TestStageLayers function loads the shipped defaults and stage layers, without any local layer,
+// and checks each stage has its own table prefix
+func TestStageLayers(t *testing.T) {
+
+	files := make(map[string]string)
+	for _, name := range []string{"defaults.yml", "defaults.dev.yml", "defaults.stage.yml", "defaults.prod.yml", "defaults.test.yml"} {
+		doc, err := ioutil.ReadFile(name)
+		assert.NoError(t, err)
+		files[name] = string(doc)
+	}
+	dir := writeLayers(t, files)
+
+	for stage, prefix := range map[string]string{"dev": "GDS_dev_", "stage": "GDS_stage_", "prod": "GDS_", "test": "GDS_test_"} {
+		setStage(t, stage)
+		withDefs(t, &defaults{})
+		c := &Config{DefaultsFilePath: filepath.Join(dir, "defaults.yml")}
+		assert.NoError(t, c.setDefaults(), stage)
+		assert.Equal(t, prefix, defs.Dynamo.TablePrefix, stage)
+		assert.Equal(t, filepath.Join(dir, "defaults."+stage+".yml"), c.origins["Dynamo.TablePrefix"].Name, stage)
+	}
+}
+
+func TestLayerPath(t *testing.T) {
+	assert.Equal(t, "/app/defaults.test.yml", layerPath("/app/defaults.yml", "test"))
+	assert.Equal(t, "defaults.local.yml", layerPath("defaults.yml", localLayer))
+}
@@ -0,0 +1,332 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Source string identifies where a config value was loaded from
+type Source string
+
+// Source constants
+const (
+	SourceFile Source = "file"
+	SourceEnv  Source = "env"
+	SourceSSM  Source = "ssm"
+)
+
+// Origin struct records the source of a config value, Name is the file path, env var or parameter name
+type Origin struct {
+	Name   string
+	Source Source
+}
+
+// Setting struct is a single config value as reported by Settings
+type Setting struct {
+	Key    string
+	Origin *Origin
+	Secret bool
+	Value  string
+}
+
+const redacted = "********"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// field struct is a settable leaf of the defaults struct
+// key is the dotted path of field names, e.g. Dynamo.Region
+type field struct {
+	key      string
+	required bool
+	secret   bool
+	value    reflect.Value
+}
+
+// fields function returns the leaf fields of the struct target points to
+func fields(target interface{}) []*field {
+	return walkFields(reflect.ValueOf(target), "")
+}
+
+// walkFields function walks the struct, allocating nil struct pointers, and returns its leaf fields
+func walkFields(v reflect.Value, prefix string) (fs []*field) {
+
+	v = reflect.Indirect(v)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		fv := v.Field(i)
+		key := prefix + sf.Name
+
+		if sf.Type.Kind() == reflect.Ptr && sf.Type.Elem().Kind() == reflect.Struct {
+			if fv.IsNil() {
+				fv.Set(reflect.New(sf.Type.Elem()))
+			}
+			fs = append(fs, walkFields(fv, key+".")...)
+			continue
+		}
+		if sf.Type.Kind() == reflect.Struct {
+			fs = append(fs, walkFields(fv, key+".")...)
+			continue
+		}
+
+		fs = append(fs, &field{
+			key:      key,
+			required: sf.Tag.Get("required") == "true",
+			secret:   sf.Tag.Get("secret") == "true",
+			value:    fv,
+		})
+	}
+	return fs
+}
+
+// fieldMap function indexes fields by their normalised key
+func fieldMap(fs []*field) map[string]*field {
+	m := make(map[string]*field, len(fs))
+	for _, f := range fs {
+		m[normaliseKey(f.key)] = f
+	}
+	return m
+}
+
+// normaliseKey function lower cases a key and joins its parts with dots
+// Underscores, double underscores, slashes and dots all separate nested keys,
+// so Dynamo_Region, DYNAMO__REGION and Dynamo/Region all name Dynamo.Region
+func normaliseKey(key string) string {
+	return strings.ToLower(strings.Join(splitKey(key), "."))
+}
+
+// splitKey function splits a key into its nested parts, keeping their case
+func splitKey(key string) []string {
+	return strings.FieldsFunc(key, func(r rune) bool {
+		return r == '_' || r == '/' || r == '.'
+	})
+}
+
+// lookupField function finds the field a key names
+// A key one part below a map field, e.g. Dynamo_Tables_FuelSale, names an entry of that map and entry is returned
+func lookupField(fm map[string]*field, key string) (f *field, entry string, ok bool) {
+
+	parts := splitKey(key)
+	if f, ok = fm[normaliseKey(key)]; ok {
+		return f, "", true
+	}
+	if len(parts) < 2 {
+		return nil, "", false
+	}
+	f, ok = fm[normaliseKey(strings.Join(parts[:len(parts)-1], "."))]
+	if !ok || f.value.Kind() != reflect.Map {
+		return nil, "", false
+	}
+	return f, parts[len(parts)-1], true
+}
+
+// setField function sets the field, or the map entry when entry is not empty
+func setField(f *field, entry, raw string) error {
+
+	if entry == "" {
+		return setValue(f.value, raw)
+	}
+	if f.value.Type().Key().Kind() != reflect.String || f.value.Type().Elem().Kind() != reflect.String {
+		return fmt.Errorf("unsupported map type %s", f.value.Type())
+	}
+	if f.value.IsNil() {
+		f.value.Set(reflect.MakeMap(f.value.Type()))
+	}
+	f.value.SetMapIndex(reflect.ValueOf(entry), reflect.ValueOf(raw))
+	return nil
+}
+
+// setValue function parses raw into the field's type
+// Lists are comma separated, maps are comma separated key=value pairs, durations use time.ParseDuration
+func setValue(v reflect.Value, raw string) error {
+
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported list type %s", v.Type())
+		}
+		var list []string
+		for _, s := range strings.Split(raw, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				list = append(list, s)
+			}
+		}
+		v.Set(reflect.ValueOf(list).Convert(v.Type()))
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String || v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported map type %s", v.Type())
+		}
+		m := make(map[string]string)
+		for _, s := range strings.Split(raw, ",") {
+			if s = strings.TrimSpace(s); s == "" {
+				continue
+			}
+			kv := strings.SplitN(s, "=", 2)
+			if len(kv) != 2 {
+				return fmt.Errorf("invalid map entry %q, expected key=value", s)
+			}
+			m[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+		v.Set(reflect.ValueOf(m).Convert(v.Type()))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// formatValue function returns the value as it would be written in an env var
+func formatValue(v reflect.Value) string {
+
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).String()
+	}
+	if v.Kind() == reflect.Slice {
+		list := make([]string, v.Len())
+		for i := range list {
+			list[i] = fmt.Sprint(v.Index(i).Interface())
+		}
+		return strings.Join(list, ",")
+	}
+	if v.Kind() == reflect.Map {
+		list := make([]string, 0, v.Len())
+		for _, k := range v.MapKeys() {
+			list = append(list, fmt.Sprintf("%v=%v", k.Interface(), v.MapIndex(k).Interface()))
+		}
+		sort.Strings(list)
+		return strings.Join(list, ",")
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// applyEnv function sets fields from env vars, environ is in os.Environ form
+// Env var names match field keys case insensitively, see normaliseKey
+func applyEnv(fm map[string]*field, environ []string, origins map[string]*Origin) error {
+
+	for _, kv := range environ {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			continue
+		}
+		f, entry, ok := lookupField(fm, parts[0])
+		if !ok {
+			continue
+		}
+		if err := setField(f, entry, parts[1]); err != nil {
+			return fmt.Errorf("Invalid value for %s from env %s: %s", f.key, parts[0], err)
+		}
+		origins[f.key] = &Origin{Name: parts[0], Source: SourceEnv}
+	}
+	return nil
+}
+
+// fileKeys function returns the dotted keys present in a parsed yaml document
+func fileKeys(doc map[interface{}]interface{}, prefix string) (keys []string) {
+	for k, v := range doc {
+		key := prefix + fmt.Sprint(k)
+		if m, ok := v.(map[interface{}]interface{}); ok {
+			keys = append(keys, fileKeys(m, key+".")...)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// fileValues function flattens a parsed yaml document into values keyed by slash separated names
+// Lists are joined with commas, matching setValue, and empty values are skipped
+func fileValues(doc map[interface{}]interface{}, prefix string) map[string]string {
+
+	values := make(map[string]string)
+	for k, v := range doc {
+		key := prefix + fmt.Sprint(k)
+		switch val := v.(type) {
+		case map[interface{}]interface{}:
+			for nk, nv := range fileValues(val, key+"/") {
+				values[nk] = nv
+			}
+		case []interface{}:
+			list := make([]string, len(val))
+			for i, item := range val {
+				list[i] = fmt.Sprint(item)
+			}
+			values[key] = strings.Join(list, ",")
+		case nil:
+			continue
+		default:
+			values[key] = fmt.Sprint(val)
+		}
+	}
+	return values
+}
+
+// validateRequired function returns an error listing required fields without a value
+func validateRequired(fs []*field) error {
+
+	var missing []string
+	for _, f := range fs {
+		if f.required && f.value.IsZero() {
+			missing = append(missing, f.key)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("Missing required config values: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// settings function lists every field with its origin, redacting secrets that are set
+func settings(fs []*field, origins map[string]*Origin) []*Setting {
+
+	list := make([]*Setting, 0, len(fs))
+	for _, f := range fs {
+		s := &Setting{Key: f.key, Origin: origins[f.key], Secret: f.secret, Value: formatValue(f.value)}
+		if f.secret && s.Value != "" {
+			s.Value = redacted
+		}
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Key < list[j].Key })
+	return list
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testNested struct {
+	Endpoint string
+	Region   string `required:"true"`
+	Tables   map[string]string
+}
+
+type testTarget struct {
+	Count   int
+	Enabled bool
+	List    []string
+	Nested  *testNested
+	Rate    float64
+	Secret  string `secret:"true"`
+	Timeout time.Duration
+}
+
+func TestApplyEnvNested(t *testing.T) {
+
+	target := &testTarget{}
+	origins := make(map[string]*Origin)
+	environ := []string{
+		"Nested_Region=ca-central-1",
+		"NESTED__ENDPOINT=http://localhost:8000",
+		"Unrelated=value",
+		"Count=",
+	}
+
+	err := applyEnv(fieldMap(fields(target)), environ, origins)
+	assert.NoError(t, err)
+	assert.Equal(t, "ca-central-1", target.Nested.Region)
+	assert.Equal(t, "http://localhost:8000", target.Nested.Endpoint)
+	assert.Equal(t, &Origin{Name: "NESTED__ENDPOINT", Source: SourceEnv}, origins["Nested.Endpoint"])
+	assert.Nil(t, origins["Count"])
+	assert.Len(t, origins, 2)
+}
+
+func TestApplyEnvTyped(t *testing.T) {
+
+	target := &testTarget{}
+	environ := []string{
+		"Count=12",
+		"Enabled=true",
+		"List=a@example.com, b@example.com,",
+		"Rate=0.25",
+		"Timeout=1m30s",
+	}
+
+	err := applyEnv(fieldMap(fields(target)), environ, make(map[string]*Origin))
+	assert.NoError(t, err)
+	assert.Equal(t, 12, target.Count)
+	assert.True(t, target.Enabled)
+	assert.Equal(t, []string{"a@example.com", "b@example.com"}, target.List)
+	assert.Equal(t, 0.25, target.Rate)
+	assert.Equal(t, 90*time.Second, target.Timeout)
+
+	err = applyEnv(fieldMap(fields(target)), []string{"Count=twelve"}, make(map[string]*Origin))
+	assert.EqualError(t, err, `Invalid value for Count from env Count: strconv.ParseInt: parsing "twelve": invalid syntax`)
+}
+
+func TestApplyEnvMap(t *testing.T) {
+
+	target := &testTarget{}
+	origins := make(map[string]*Origin)
+	fm := fieldMap(fields(target))
+
+	err := applyEnv(fm, []string{"Nested_Tables=FuelSale=GDS_test_FuelSale, ImportLog=tmp_ImportLog"}, origins)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"FuelSale": "GDS_test_FuelSale", "ImportLog": "tmp_ImportLog"}, target.Nested.Tables)
+
+	err = applyEnv(fm, []string{"NESTED__TABLES__Station=tmp_Station"}, origins)
+	assert.NoError(t, err)
+	assert.Equal(t, "tmp_Station", target.Nested.Tables["Station"])
+	assert.Equal(t, SourceEnv, origins["Nested.Tables"].Source)
+
+	err = applyEnv(fm, []string{"Nested_Tables=FuelSale"}, origins)
+	assert.Error(t, err)
+
+	_, _, ok := lookupField(fm, "Nested_Region_Extra")
+	assert.False(t, ok)
+}
+
+func TestNormaliseKey(t *testing.T) {
+	for _, key := range []string{"Dynamo.Region", "Dynamo_Region", "DYNAMO__REGION", "dynamo/region"} {
+		assert.Equal(t, "dynamo.region", normaliseKey(key), key)
+	}
+}
+
+func TestValidateRequired(t *testing.T) {
+
+	target := &testTarget{}
+	assert.EqualError(t, validateRequired(fields(target)), "Missing required config values: Nested.Region")
+
+	target.Nested.Region = "ca-central-1"
+	assert.NoError(t, validateRequired(fields(target)))
+}
+
+func TestSettings(t *testing.T) {
+
+	target := &testTarget{Count: 3, Secret: "hunter2", Timeout: time.Second, List: []string{"a", "b"}}
+	origins := map[string]*Origin{
+		"Count":  {Name: "defaults.yml", Source: SourceFile},
+		"Secret": {Name: "/test/gdps-fs-import/Secret", Source: SourceSSM},
+	}
+
+	list := settings(fields(target), origins)
+	byKey := make(map[string]*Setting, len(list))
+	for _, s := range list {
+		byKey[s.Key] = s
+	}
+
+	assert.Equal(t, "Count", list[0].Key)
+	assert.Equal(t, "3", byKey["Count"].Value)
+	assert.Equal(t, SourceFile, byKey["Count"].Origin.Source)
+	assert.Equal(t, redacted, byKey["Secret"].Value)
+	assert.True(t, byKey["Secret"].Secret)
+	assert.Equal(t, "1s", byKey["Timeout"].Value)
+	assert.Equal(t, "a,b", byKey["List"].Value)
+	assert.Nil(t, byKey["Nested.Region"].Origin)
+
+	target.Secret = ""
+	list = settings(fields(target), origins)
+	for _, s := range list {
+		if s.Key == "Secret" {
+			assert.Equal(t, "", s.Value)
+		}
+	}
+}
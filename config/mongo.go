@@ -0,0 +1,107 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// MongoAuth string is the authentication mechanism used to connect to MongoDB
+type MongoAuth string
+
+// MongoAuth constants
+const (
+	MongoAuthNone  MongoAuth = "none"
+	MongoAuthAWS   MongoAuth = "aws"
+	MongoAuthSCRAM MongoAuth = "scram"
+	MongoAuthX509  MongoAuth = "x509"
+)
+
+// mongoConn struct holds the values used to build a connection string
+type mongoConn struct {
+	Auth        MongoAuth
+	AuthSource  string
+	CAFile      string
+	CertKeyFile string
+	Host        string
+	Name        string
+	Password    string
+	SRV         bool
+	User        string
+}
+
+// mongoConnectURL function builds a connection string as described in:
+// https://docs.mongodb.com/manual/reference/connection-string/
+// SRV hosts use TLS by default, plain hosts only when a CA bundle or client certificate is given
+func mongoConnectURL(m *mongoConn) (string, error) {
+
+	if m.Host == "" {
+		return "", errors.New("Missing MongoDB host")
+	}
+
+	q := url.Values{}
+	var userInfo string
+
+	switch m.Auth {
+	case MongoAuthNone:
+	case MongoAuthAWS:
+		q.Set("authMechanism", "MONGODB-AWS")
+		q.Set("authSource", "$external")
+	case MongoAuthSCRAM:
+		if m.User == "" || m.Password == "" {
+			return "", errors.New("MongoDB scram auth requires MongoDBUser and MongoDBPassword")
+		}
+		// the driver query unescapes user info, so '+' must be escaped too
+		userInfo = url.QueryEscape(m.User) + ":" + url.QueryEscape(m.Password) + "@"
+		q.Set("authMechanism", "SCRAM-SHA-256")
+		q.Set("authSource", "admin")
+		if m.AuthSource != "" {
+			q.Set("authSource", m.AuthSource)
+		}
+	case MongoAuthX509:
+		if m.CertKeyFile == "" {
+			return "", errors.New("MongoDB x509 auth requires MongoDBCertKeyFile")
+		}
+		q.Set("authMechanism", "MONGODB-X509")
+		q.Set("authSource", "$external")
+	default:
+		return "", fmt.Errorf("Invalid MongoDBAuth requested: %s", m.Auth)
+	}
+
+	if m.CertKeyFile != "" {
+		q.Set("tlsCertificateKeyFile", m.CertKeyFile)
+	}
+	if m.CAFile != "" {
+		q.Set("tlsCAFile", m.CAFile)
+	}
+
+	scheme := "mongodb+srv"
+	if m.SRV {
+		q.Set("retryWrites", "true")
+		q.Set("w", "majority")
+	} else {
+		scheme = "mongodb"
+		q.Set("directConnection", "true")
+		q.Set("readPreference", "primary")
+		if m.CAFile == "" && m.CertKeyFile == "" {
+			q.Set("ssl", "false")
+		}
+	}
+	if q.Get("tlsCAFile") != "" || q.Get("tlsCertificateKeyFile") != "" {
+		q.Set("tls", "true")
+	}
+
+	return fmt.Sprintf("%s://%s%s/%s?%s", scheme, userInfo, m.Host, url.PathEscape(m.Name), q.Encode()), nil
+}
+
+// mongoAuth function returns the configured auth mode, defaulting to none for the test stage and aws otherwise
+func mongoAuth(auth string, stage StageEnvironment) MongoAuth {
+	if auth != "" {
+		return MongoAuth(strings.ToLower(auth))
+	}
+	if stage == TestEnv {
+		return MongoAuthNone
+	}
+	return MongoAuthAWS
+}
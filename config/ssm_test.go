@@ -0,0 +1,119 @@
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSSM serves pages of parameters keyed by NextToken, the first page has an empty token
+type fakeSSM struct {
+	ssmiface.SSMAPI
+	err    error
+	inputs []*ssm.GetParametersByPathInput
+	pages  map[string]*ssm.GetParametersByPathOutput
+}
+
+func (f *fakeSSM) GetParametersByPath(input *ssm.GetParametersByPathInput) (*ssm.GetParametersByPathOutput, error) {
+	f.inputs = append(f.inputs, input)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.pages[aws.StringValue(input.NextToken)], nil
+}
+
+func param(name, value string) *ssm.Parameter {
+	return &ssm.Parameter{Name: aws.String(name), Value: aws.String(value)}
+}
+
+// withDefs replaces the package defaults for the duration of a test
+func withDefs(t *testing.T, d *defaults) {
+	saved := defs
+	defs = d
+	t.Cleanup(func() { defs = saved })
+}
+
+func TestFetchSSMParamsPages(t *testing.T) {
+
+	svc := &fakeSSM{pages: map[string]*ssm.GetParametersByPathOutput{
+		"": {
+			Parameters: []*ssm.Parameter{param("/test/fs/MongoDBHost", "mongo.local")},
+			NextToken:  aws.String("page2"),
+		},
+		"page2": {
+			Parameters: []*ssm.Parameter{param("/test/fs/Dynamo/Region", "us-east-1")},
+			NextToken:  aws.String("page3"),
+		},
+		"page3": {
+			Parameters: []*ssm.Parameter{param("/test/fs/SMTPPassword", "hunter2")},
+		},
+	}}
+
+	params, err := fetchSSMParams(svc, "/test/fs")
+	assert.NoError(t, err)
+	assert.Len(t, params, 3)
+	assert.Len(t, svc.inputs, 3)
+	for _, in := range svc.inputs {
+		assert.Equal(t, "/test/fs", aws.StringValue(in.Path))
+		assert.True(t, aws.BoolValue(in.Recursive))
+		assert.True(t, aws.BoolValue(in.WithDecryption))
+	}
+}
+
+func TestFetchSSMParamsError(t *testing.T) {
+	svc := &fakeSSM{err: errors.New("AccessDenied")}
+	_, err := fetchSSMParams(svc, "/test/fs")
+	assert.EqualError(t, err, "AccessDenied")
+}
+
+func TestApplyParamsNested(t *testing.T) {
+
+	withDefs(t, &defaults{Stage: "test", SsmPath: "fs"})
+	c := &Config{}
+	c.Stage = TestEnv
+
+	err := c.applyParams(c.ssmPath(), []*ssm.Parameter{
+		param("/test/fs/Dynamo/Region", "us-east-1"),
+		param("/test/fs/NotifyTimeout", "30s"),
+		param("/test/fs/Unknown/Key", "ignored"),
+	}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "us-east-1", defs.Dynamo.Region)
+	assert.Equal(t, "30s", defs.NotifyTimeout.String())
+	assert.Equal(t, &Origin{Name: "/test/fs/Dynamo/Region", Source: SourceSSM}, c.origins["Dynamo.Region"])
+
+	err = c.applyParams(c.ssmPath(), []*ssm.Parameter{param("/test/fs/NotifyTimeout", "soon")}, "")
+	assert.Error(t, err)
+}
+
+func TestSetSSMParamsFile(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "config")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	fp := filepath.Join(dir, "params.yml")
+	doc := "MongoDBHost: mongo.local\nMailTo:\n  - a@example.com\n  - b@example.com\nDynamo:\n  Endpoint: http://localhost:8000\nSMTPUser:\n"
+	assert.NoError(t, ioutil.WriteFile(fp, []byte(doc), 0600))
+
+	withDefs(t, &defaults{Stage: "test", SsmPath: "fs", SsmParamsFile: fp, SMTPUser: "kept"})
+	c := &Config{}
+	c.Stage = TestEnv
+
+	assert.NoError(t, c.setSSMParams())
+	assert.Equal(t, "mongo.local", defs.MongoDBHost)
+	assert.Equal(t, []string{"a@example.com", "b@example.com"}, defs.MailTo)
+	assert.Equal(t, "http://localhost:8000", defs.Dynamo.Endpoint)
+	assert.Equal(t, "kept", defs.SMTPUser)
+	assert.Equal(t, &Origin{Name: fp, Source: SourceFile}, c.origins["Dynamo.Endpoint"])
+
+	withDefs(t, &defaults{Stage: "test", SsmPath: "fs", SsmParamsFile: filepath.Join(dir, "missing.yml")})
+	assert.Error(t, c.setSSMParams())
+}
@@ -1,32 +1,84 @@
 package config
 
+import "time"
+
 // Config struct
+// SkipSSM loads the defaults file and environment only, for local runs without AWS access
 type Config struct {
 	config
 	DefaultsFilePath string
+	SkipSSM          bool
+	origins          map[string]*Origin
 }
 
 // defaults struct
+// Fields tagged required must have a value once all sources are loaded,
+// fields tagged secret are redacted by Settings
 type defaults struct {
-	AWSRegion   string  `yaml:"AWSRegion"`
-	Dynamo      *Dynamo `yaml:"Dynamo"`
-	MongoDBHost string  `yaml:"MongoDBHost"`
-	MongoDBName string  `yaml:"MongoDBName"`
-	SsmPath     string  `yaml:"SsmPath"`
-	Stage       string  `yaml:"Stage"`
+	AWSRegion           string        `yaml:"AWSRegion" required:"true"`
+	ArchiveDir          string        `yaml:"ArchiveDir"`
+	Dynamo              *Dynamo       `yaml:"Dynamo"`
+	MailFrom            string        `yaml:"MailFrom"`
+	MailTo              []string      `yaml:"MailTo"`
+	MongoDBAuth         string        `yaml:"MongoDBAuth"`
+	MongoDBAuthSource   string        `yaml:"MongoDBAuthSource"`
+	MongoDBBatchSize    int           `yaml:"MongoDBBatchSize"`
+	MongoDBCAFile       string        `yaml:"MongoDBCAFile"`
+	MongoDBCertKeyFile  string        `yaml:"MongoDBCertKeyFile"`
+	MongoDBHost         string        `yaml:"MongoDBHost" required:"true"`
+	MongoDBName         string        `yaml:"MongoDBName" required:"true"`
+	MongoDBPassword     string        `yaml:"MongoDBPassword" secret:"true"`
+	MongoDBUser         string        `yaml:"MongoDBUser"`
+	NotifyTimeout       time.Duration `yaml:"NotifyTimeout"`
+	NotifyTopicArn      string        `yaml:"NotifyTopicArn"`
+	NotifyWebhookSecret string        `yaml:"NotifyWebhookSecret" secret:"true"`
+	NotifyWebhookURL    string        `yaml:"NotifyWebhookURL"`
+	S3Bucket            string        `yaml:"S3Bucket"`
+	SMTPAddr            string        `yaml:"SMTPAddr"`
+	SMTPPassword        string        `yaml:"SMTPPassword" secret:"true"`
+	SMTPUser            string        `yaml:"SMTPUser"`
+	SsmParamsFile       string        `yaml:"SsmParamsFile"`
+	SsmPath             string        `yaml:"SsmPath" required:"true"`
+	Stage               string        `yaml:"Stage" required:"true"`
 }
 
 type config struct {
-	AWSRegion         string
-	Dynamo            *Dynamo
-	MongoDBConnectURL string
-	MongoDBName       string
-	Stage             StageEnvironment
+	AWSRegion           string
+	ArchiveDir          string
+	Dynamo              *Dynamo
+	MailFrom            string
+	MailTo              []string
+	MongoDBBatchSize    int
+	MongoDBConnectURL   string
+	MongoDBName         string
+	NotifyTimeout       time.Duration
+	NotifyTopicArn      string
+	NotifyWebhookSecret string
+	NotifyWebhookURL    string
+	S3Bucket            string
+	SMTPAddr            string
+	SMTPPassword        string
+	SMTPUser            string
+	Stage               StageEnvironment
 }
 
 // Dynamo struct
+// Credentials is empty for the default chain, static for AccessKeyID and SecretAccessKey,
+// or profile for a named shared credentials profile.
+// MaxRetries and the retry delays use the sdk defaults when zero.
+// Table names are TablePrefix followed by the table, e.g. GDS_stage_FuelSale,
+// Tables overrides individual names keyed by table, e.g. FuelSale: GDS_FuelSaleArchive
 type Dynamo struct {
-	APIVersion string `yaml:"APIVersion"`
-	Endpoint   string `yaml:"Endpoint"`
-	Region     string `yaml:"Region"`
+	APIVersion      string            `yaml:"APIVersion"`
+	AccessKeyID     string            `yaml:"AccessKeyID"`
+	Credentials     string            `yaml:"Credentials"`
+	Endpoint        string            `yaml:"Endpoint"`
+	MaxRetries      int               `yaml:"MaxRetries"`
+	MaxRetryDelay   time.Duration     `yaml:"MaxRetryDelay"`
+	MinRetryDelay   time.Duration     `yaml:"MinRetryDelay"`
+	Profile         string            `yaml:"Profile"`
+	Region          string            `yaml:"Region" required:"true"`
+	SecretAccessKey string            `yaml:"SecretAccessKey" secret:"true"`
+	TablePrefix     string            `yaml:"TablePrefix"`
+	Tables          map[string]string `yaml:"Tables"`
 }
@@ -0,0 +1,25 @@
+package export
+
+import "github.com/pulpfree/gsales-fs-export/archive"
+
+// archive method writes an audit copy of a completed export when an archive is configured
+// The export has already been written to dynamo, so failures are logged rather than returned
+func (e *Exporter) archive(b *archive.Batch) {
+
+	arc, err := archive.FromConfig(e.cfg)
+	if err != nil {
+		e.logger().Errorf("Error creating archiver: %s", err)
+		return
+	}
+	if arc == nil {
+		return
+	}
+
+	loc, err := arc.Write(b)
+	if err != nil {
+		e.logger().Errorf("Error archiving %s export %d: %s", b.Result.ImportType, b.Result.ImportTS, err)
+		return
+	}
+	b.Result.ArchivePath = loc
+	e.logger().Infof("Archived %s export to %s", b.Result.ImportType, loc)
+}
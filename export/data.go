@@ -0,0 +1,66 @@
+package export
+
+import (
+	"context"
+
+	"github.com/pulpfree/gsales-fs-export/model"
+)
+
+// Data method fetches previously exported sales for the request range, resolving station names
+func (e *Exporter) Data(ctx context.Context) (data *model.ExportData, err error) {
+	return e.fetchData(ctx, e.Request.ExportType)
+}
+
+// WorkbookData method fetches both fuel and propane sales for the request range
+func (e *Exporter) WorkbookData(ctx context.Context) (data *model.ExportData, err error) {
+	return e.fetchData(ctx, model.FuelType, model.PropaneType)
+}
+
+// fetchData method stops deadlineReserve before the ctx deadline, leaving time to respond
+func (e *Exporter) fetchData(ctx context.Context, types ...model.ExportType) (data *model.ExportData, err error) {
+
+	ctx, cancel := withReserve(ctx)
+	defer cancel()
+
+	// Set MongoDB connection
+	mongo, err := e.mongoDB(ctx)
+	if err != nil {
+		e.logger().Errorf("Error connecting to mongo: %s", err)
+		return data, err
+	}
+	defer mongo.Close()
+
+	data = &model.ExportData{ExportType: e.Request.ExportType}
+
+	for _, tp := range types {
+		switch tp {
+		case model.FuelType:
+			data.Fuel, err = mongo.FetchExportedFuelSales(ctx, e.Request)
+			if err != nil {
+				e.logger().Errorf("Error fetching fuel sales: %s", err)
+				return data, err
+			}
+			stations, err := mongo.FetchStationNodes(ctx)
+			if err != nil {
+				e.logger().Errorf("Error fetching station nodes: %s", err)
+				return data, err
+			}
+			data.Stations = make(map[string]string, len(stations))
+			for _, st := range stations {
+				data.Stations[st.ID.Hex()] = st.Name
+			}
+
+		case model.PropaneType:
+			data.Propane, err = mongo.FetchExportedPropaneSales(ctx, e.Request)
+			if err != nil {
+				e.logger().Errorf("Error fetching propane sales: %s", err)
+				return data, err
+			}
+
+		default:
+			return data, ErrInvalidExportType
+		}
+	}
+
+	return data, err
+}
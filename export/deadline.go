@@ -0,0 +1,73 @@
+package export
+
+import (
+	"context"
+	"time"
+)
+
+// Export stage constants, reported in the Progress of an export stopped by the deadline
+const (
+	StageCompile = "compile"
+	StageFetch   = "fetch"
+	StageWrite   = "write"
+)
+
+// deadlineReserve is held back from the invocation deadline so a stopped export can still log,
+// record metrics, notify and respond before Lambda kills it
+const deadlineReserve = 3 * time.Second
+
+// noDeadlineBudget bounds an export whose context has no deadline, e.g. when run from the command line
+const noDeadlineBudget = 15 * time.Minute
+
+// stageShares is the share of the time left that each stage may use, leaving the rest for the stages after it
+// The write leaves a fifth for archiving the batch and emailing the summary
+var stageShares = map[string]float64{
+	StageCompile: 0.6,
+	StageFetch:   0.5,
+	StageWrite:   0.8,
+}
+
+// withReserve function returns a context that ends deadlineReserve before the parent's deadline
+func withReserve(ctx context.Context) (context.Context, context.CancelFunc) {
+	if dl, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(ctx, dl.Add(-deadlineReserve))
+	}
+	return context.WithTimeout(ctx, noDeadlineBudget)
+}
+
+// stageTimeout function returns the stage's share of the time left before the context's deadline
+func stageTimeout(ctx context.Context, stage string, now time.Time) time.Duration {
+
+	share, ok := stageShares[stage]
+	if !ok {
+		share = 1
+	}
+	dl, ok := ctx.Deadline()
+	if !ok {
+		return time.Duration(share * float64(noDeadlineBudget))
+	}
+	left := dl.Sub(now)
+	if left <= 0 {
+		return 0
+	}
+	return time.Duration(share * float64(left))
+}
+
+// stage method runs fn with a context limited to the stage's timeout, recording the stage in the progress
+// An error caused by the stage or invocation running out of time is returned as ErrDeadline
+func (e *Exporter) stage(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+
+	sctx, cancel := context.WithTimeout(ctx, stageTimeout(ctx, name, time.Now()))
+	defer cancel()
+
+	if err := fn(sctx); err != nil {
+		if sctx.Err() == context.DeadlineExceeded {
+			e.progress.Stopped = name
+			return ErrDeadline.Wrap(name, err)
+		}
+		return err
+	}
+	e.progress.Completed = append(e.progress.Completed, name)
+
+	return nil
+}
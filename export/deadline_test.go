@@ -0,0 +1,76 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pulpfree/gsales-fs-export/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestWithReserve function
+func TestWithReserve(t *testing.T) {
+
+	dl := time.Now().Add(30 * time.Second)
+	parent, cancel := context.WithDeadline(context.Background(), dl)
+	defer cancel()
+
+	ctx, cancel := withReserve(parent)
+	defer cancel()
+	got, ok := ctx.Deadline()
+	assert.True(t, ok)
+	assert.Equal(t, dl.Add(-deadlineReserve), got)
+
+	ctx, cancel = withReserve(context.Background())
+	defer cancel()
+	got, ok = ctx.Deadline()
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(noDeadlineBudget), got, time.Second)
+}
+
+// TestStageTimeout function
+func TestStageTimeout(t *testing.T) {
+
+	now := time.Now()
+	ctx, cancel := context.WithDeadline(context.Background(), now.Add(20*time.Second))
+	defer cancel()
+
+	assert.Equal(t, 12*time.Second, stageTimeout(ctx, StageCompile, now))
+	assert.Equal(t, 10*time.Second, stageTimeout(ctx, StageFetch, now))
+	assert.Equal(t, 16*time.Second, stageTimeout(ctx, StageWrite, now))
+	assert.Equal(t, time.Duration(0), stageTimeout(ctx, StageWrite, now.Add(time.Minute)))
+	assert.Equal(t, time.Duration(0.8*float64(noDeadlineBudget)), stageTimeout(context.Background(), StageWrite, now))
+
+	// every stage leaves time for the stages after it
+	for stage, share := range stageShares {
+		assert.True(t, share > 0 && share < 1, stage)
+	}
+}
+
+// TestStageProgress function
+func TestStageProgress(t *testing.T) {
+
+	e := &Exporter{progress: &model.Progress{}}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err := e.stage(ctx, StageCompile, func(ctx context.Context) error { return nil })
+	assert.NoError(t, err)
+
+	// a stage running past its share of the time stops with ErrDeadline
+	err = e.stage(ctx, StageFetch, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	assert.True(t, errors.Is(err, ErrDeadline))
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, []string{StageCompile}, e.progress.Completed)
+	assert.Equal(t, StageFetch, e.progress.Stopped)
+
+	// other failures are returned as is
+	boom := errors.New("boom")
+	err = e.stage(ctx, StageWrite, func(ctx context.Context) error { return boom })
+	assert.Equal(t, boom, err)
+}
@@ -0,0 +1,60 @@
+package export
+
+import (
+	"context"
+
+	"github.com/pulpfree/gsales-fs-export/mail"
+	"github.com/pulpfree/gsales-fs-export/model"
+	"github.com/pulpfree/gsales-fs-export/model/mongo"
+	"github.com/pulpfree/gsales-fs-export/render"
+)
+
+// emailFuelSummary method mails the fuel export summary with a comparison to the prior period
+// The export has already been written to dynamo, so failures are logged rather than returned
+func (e *Exporter) emailFuelSummary(ctx context.Context, db *mongo.MDB, sales []*model.FuelSalesExport, stations []model.StationNodes, res *model.DnImportRes) {
+
+	m := e.mailer()
+	if m == nil {
+		return
+	}
+
+	data := &model.ExportData{ExportType: model.FuelType, Fuel: sales, Stations: make(map[string]string, len(stations))}
+	for _, st := range stations {
+		data.Stations[st.ID.Hex()] = st.Name
+	}
+
+	prevSales, err := db.FetchExportedFuelSales(ctx, priorPeriod(e.Request))
+	if err != nil {
+		e.logger().Errorf("Error fetching prior period fuel sales: %s", err)
+	}
+
+	summary, err := render.FuelSummary(data, &model.ExportData{Fuel: prevSales}, res)
+	if err != nil {
+		e.logger().Errorf("Error rendering fuel summary: %s", err)
+		return
+	}
+
+	err = m.Send(&mail.Message{
+		From:    e.cfg.MailFrom,
+		HTML:    summary.HTML,
+		Subject: summary.Subject,
+		Text:    summary.Text,
+		To:      e.cfg.MailTo,
+	})
+	if err != nil {
+		e.logger().Errorf("Error sending fuel summary email: %s", err)
+	}
+}
+
+// priorPeriod function returns the request for the days immediately before the requested range,
+// the range shifted back by its own length so the two never overlap
+func priorPeriod(req *model.Request) *model.Request {
+
+	days := int(req.DateEnd.Sub(req.DateStart).Hours()/24) + 1
+
+	prev := *req
+	prev.DateStart = req.DateStart.AddDate(0, 0, -days)
+	prev.DateEnd = req.DateEnd.AddDate(0, 0, -days)
+
+	return &prev
+}
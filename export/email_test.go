@@ -0,0 +1,35 @@
+package export
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pulpfree/gsales-fs-export/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPriorPeriod function
+func TestPriorPeriod(t *testing.T) {
+
+	date := func(d string) time.Time {
+		tm, _ := time.Parse(timeForm, d)
+		return tm
+	}
+
+	tests := []struct {
+		start, end         string
+		prevStart, prevEnd string
+	}{
+		{"2023-06-01", "2023-06-01", "2023-05-31", "2023-05-31"},
+		{"2023-06-05", "2023-06-11", "2023-05-29", "2023-06-04"},
+		{"2023-06-01", "2023-06-14", "2023-05-18", "2023-05-31"},
+	}
+	for _, tt := range tests {
+		req := &model.Request{DateStart: date(tt.start), DateEnd: date(tt.end), ExportType: model.FuelType}
+		prev := priorPeriod(req)
+		assert.Equal(t, date(tt.prevStart), prev.DateStart, tt.start)
+		assert.Equal(t, date(tt.prevEnd), prev.DateEnd, tt.start)
+		assert.True(t, prev.DateEnd.Before(req.DateStart), tt.start)
+		assert.Equal(t, model.FuelType, prev.ExportType)
+	}
+}
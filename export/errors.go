@@ -0,0 +1,13 @@
+package export
+
+import "github.com/pulpfree/gsales-fs-export/model"
+
+// Export error sentinels
+var (
+	ErrDeadline          = &model.Error{Code: "export_deadline", Kind: model.KindTimeout, Msg: "Export stopped before the invocation deadline"}
+	ErrDataQuality       = &model.Error{Code: "data_quality_blocked", Kind: model.KindConflict, Msg: "Export blocked by data quality checks"}
+	ErrMissingDays       = &model.Error{Code: "missing_sales_days", Kind: model.KindConflict, Msg: "Stations are missing sales days in the requested range"}
+	ErrInvalidExportType = &model.Error{Code: "invalid_export_type", Kind: model.KindInvalid, Msg: "Invalid export type requested"}
+	ErrNoFuelSales       = &model.Error{Code: "fuel_sales_not_found", Kind: model.KindNotFound, Msg: "Error fetching exported fuel sales"}
+	ErrNoPropaneSales    = &model.Error{Code: "propane_sales_not_found", Kind: model.KindNotFound, Msg: "Error fetching exported propane sales"}
+)
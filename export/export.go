@@ -1,33 +1,177 @@
 package export
 
 import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
 	"github.com/pulpfree/gsales-fs-export/config"
+	"github.com/pulpfree/gsales-fs-export/logging"
+	"github.com/pulpfree/gsales-fs-export/mail"
+	"github.com/pulpfree/gsales-fs-export/metrics"
 	"github.com/pulpfree/gsales-fs-export/model"
+	"github.com/pulpfree/gsales-fs-export/model/mongo"
+	"github.com/pulpfree/gsales-fs-export/notify"
+	"github.com/pulpfree/gsales-fs-export/quality"
+	"github.com/pulpfree/gsales-fs-export/tracing"
 )
 
 const timeForm = "2006-01-02"
 
 // Exporter struct
+// Checks are the data quality rules run before writing to DynamoDB
+// Mailer, when set, is sent the fuel export summary email, left nil it is built from the config on first use
+// Mongo provides the connection, shared by every exporter in the process for the configured database
+// Metrics are written to stdout in CloudWatch embedded metric format when Process finishes
+// Notifier, when set, is sent a summary once Process finishes, left nil it is built from the config on first use
+// Log carries the request fields, and the correlation id when set by the caller
 type Exporter struct {
-	Request *model.Request
-	cfg     *config.Config
+	Checks   *quality.Engine
+	Log      *log.Entry
+	Mailer   mail.Mailer
+	Metrics  *metrics.Logger
+	Mongo    *mongo.Manager
+	Notifier notify.Notifier
+	Request  *model.Request
+	cfg      *config.Config
+	progress *model.Progress
+	span     *tracing.Span
 }
 
 // New function
 func New(r *model.Request, cfg *config.Config) *Exporter {
-	e := &Exporter{Request: r, cfg: cfg}
+	e := &Exporter{Checks: quality.Default(), Request: r, cfg: cfg}
+	e.Mongo = mongo.SharedManager(cfg.GetMongoConnectURL(), cfg.MongoDBName)
+	e.Log = logging.Request(nil, cfg.GetStageEnv(), r)
+	e.Metrics = metrics.New(os.Stdout, metrics.Namespace, map[string]string{
+		"ExportType": string(r.ExportType),
+		"Stage":      string(cfg.GetStageEnv()),
+	})
+
 	return e
 }
 
 // Process request function
-func (e *Exporter) Process() (res *model.DnImportRes, err error) {
+// Stages are given a share of the time left before the ctx deadline, less a reserve for reporting.
+// An export that runs out of time returns ErrDeadline with the Progress made set on the result.
+func (e *Exporter) Process(ctx context.Context) (res *model.DnImportRes, err error) {
+
+	t := time.Now()
+	ctx, cancel := withReserve(ctx)
+	defer cancel()
+	e.progress = &model.Progress{}
+
+	// Trace the export when the invocation is sampled by X-Ray
+	tracer, tErr := tracing.FromEnv()
+	if tErr != nil {
+		e.logger().Errorf("Error creating tracer: %s", tErr)
+	}
+	defer tracer.Close()
+	e.span = tracer.Start("export")
+	e.span.Annotate("exportType", string(e.Request.ExportType))
+	e.span.Annotate("stage", string(e.cfg.GetStageEnv()))
 
 	switch e.Request.ExportType {
 	case model.FuelType:
-		res, err = e.fuel()
+		res, err = e.fuel(ctx)
 	case model.PropaneType:
-		res, err = e.propane()
+		res, err = e.propane(ctx)
+	default:
+		err = ErrInvalidExportType
+	}
+	if errors.Is(err, ErrDeadline) && res != nil {
+		res.Progress = e.progress
+		e.logger().WithField("progress", e.progress).Warn("Export stopped before the invocation deadline")
 	}
+	e.span.End(err)
+	e.record(res, t, err)
+	e.notify(res, time.Since(t), err)
 
 	return res, err
 }
+
+// record method writes the end to end metrics for the export
+func (e *Exporter) record(res *model.DnImportRes, start time.Time, err error) {
+
+	e.Metrics.Since(nil, "ExportDuration", start)
+	failed := 0.0
+	if err != nil {
+		failed = 1
+	}
+	e.Metrics.Put("ExportFailures", failed, metrics.Count)
+	if res != nil {
+		e.Metrics.Put("RecordsExported", float64(res.RecordQuantity), metrics.Count)
+	}
+
+	if mErr := e.Metrics.Flush(); mErr != nil {
+		e.logger().Errorf("Error writing metrics: %s", mErr)
+	}
+}
+
+// mongoDB method returns a connection for the request, carrying its log, metrics and trace
+func (e *Exporter) mongoDB(ctx context.Context) (*mongo.MDB, error) {
+
+	if e.Mongo == nil {
+		e.Mongo = mongo.SharedManager(e.cfg.GetMongoConnectURL(), e.cfg.MongoDBName)
+	}
+	db, err := e.Mongo.DB(ctx)
+	if err != nil {
+		return nil, err
+	}
+	db.BatchSize = e.cfg.MongoDBBatchSize
+	db.Log = e.Log
+	db.Metrics = e.Metrics
+	db.Trace = e.span
+
+	return db, nil
+}
+
+// logger method returns the request logger
+func (e *Exporter) logger() *log.Entry {
+	return logging.Or(e.Log)
+}
+
+// mailer method returns the Mailer, built from the config the first time it is needed
+// Nothing is built when no recipients are configured
+func (e *Exporter) mailer() mail.Mailer {
+
+	if e.Mailer == nil && e.cfg != nil {
+		m, err := mail.FromConfig(e.cfg)
+		if err != nil {
+			e.logger().Errorf("Error creating mailer: %s", err)
+		}
+		e.Mailer = m
+	}
+	return e.Mailer
+}
+
+// notifier method returns the Notifier, built from the config the first time it is needed
+// Nothing is built when no destination is configured
+func (e *Exporter) notifier() notify.Notifier {
+
+	if e.Notifier == nil && e.cfg != nil {
+		n, err := notify.FromConfig(e.cfg)
+		if err != nil {
+			e.logger().Errorf("Error creating notifier: %s", err)
+		}
+		e.Notifier = n
+	}
+	return e.Notifier
+}
+
+// notify method sends the export summary, failures are logged and do not fail the export
+func (e *Exporter) notify(res *model.DnImportRes, dur time.Duration, err error) {
+
+	n := e.notifier()
+	if n == nil {
+		return
+	}
+
+	s := notify.NewSummary(e.cfg.GetStageEnv(), e.Request, res, dur, err)
+	if nErr := n.Notify(s); nErr != nil {
+		e.logger().Errorf("Error sending export notification: %s", nErr)
+	}
+}
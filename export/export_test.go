@@ -0,0 +1,66 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pulpfree/gsales-fs-export/config"
+	"github.com/pulpfree/gsales-fs-export/metrics"
+	"github.com/pulpfree/gsales-fs-export/notify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubNotifier struct {
+	summaries []*notify.Summary
+}
+
+func (n *stubNotifier) Notify(s *notify.Summary) error {
+	n.summaries = append(n.summaries, s)
+	return nil
+}
+
+// TestProcessInvalidType function
+func TestProcessInvalidType(t *testing.T) {
+
+	req := testRequest()
+	req.ExportType = "diesel"
+
+	var buf bytes.Buffer
+	n := &stubNotifier{}
+	e := &Exporter{
+		Metrics:  metrics.New(&buf, metrics.Namespace, map[string]string{"Stage": "test"}),
+		Notifier: n,
+		Request:  req,
+		cfg:      &config.Config{},
+	}
+
+	_, err := e.Process(context.Background())
+	assert.True(t, errors.Is(err, ErrInvalidExportType))
+
+	require.Len(t, n.summaries, 1)
+	assert.Equal(t, notify.StatusFailure, n.summaries[0].Status)
+	assert.Equal(t, "invalid_export_type", n.summaries[0].ErrorCode)
+
+	assert.Contains(t, buf.String(), `"ExportFailures":1`)
+	assert.Contains(t, buf.String(), `"Name":"ExportDuration","Unit":"Milliseconds"`)
+}
+
+// TestNewLazyClients function checks the notifier and mailer are only built when needed and configured
+func TestNewLazyClients(t *testing.T) {
+
+	e := New(testRequest(), &config.Config{})
+	assert.Nil(t, e.Notifier)
+	assert.Nil(t, e.Mailer)
+	assert.Nil(t, e.notifier())
+	assert.Nil(t, e.mailer())
+
+	cfg := &config.Config{}
+	cfg.NotifyWebhookURL = "https://hooks.example.com/export"
+	e = New(testRequest(), cfg)
+	assert.Nil(t, e.Notifier)
+	assert.NotNil(t, e.notifier())
+	assert.NotNil(t, e.Notifier)
+}
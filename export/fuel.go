@@ -1,22 +1,21 @@
 package export
 
 import (
-	"errors"
+	"context"
+	"fmt"
 	"time"
 
-	log "github.com/sirupsen/logrus"
-
+	"github.com/pulpfree/gsales-fs-export/archive"
 	"github.com/pulpfree/gsales-fs-export/model"
 	"github.com/pulpfree/gsales-fs-export/model/dynamo"
-	"github.com/pulpfree/gsales-fs-export/model/mongo"
 )
 
-func (e *Exporter) fuel() (res *model.DnImportRes, err error) {
+func (e *Exporter) fuel(ctx context.Context) (res *model.DnImportRes, err error) {
 
 	// Set MongoDB connection
-	mongo, err := mongo.NewDB(e.cfg.GetMongoConnectURL(), e.cfg.MongoDBName)
+	mongo, err := e.mongoDB(ctx)
 	if err != nil {
-		log.Errorf("Error connecting to mongo: %s", err)
+		e.logger().Errorf("Error connecting to mongo: %s", err)
 		return res, err
 	}
 	defer mongo.Close()
@@ -24,9 +23,12 @@ func (e *Exporter) fuel() (res *model.DnImportRes, err error) {
 	// Set DynamoDB connection
 	dynamo, err := dynamo.NewDB(e.cfg.Dynamo)
 	if err != nil {
-		log.Errorf("Error connecting to dynamo: %s", err)
+		e.logger().Errorf("Error connecting to dynamo: %s", err)
 		return res, err
 	}
+	dynamo.Metrics = e.Metrics
+	dynamo.Trace = e.span
+	dynamo.Log = e.Log
 
 	t := time.Now()
 	res = &model.DnImportRes{
@@ -38,29 +40,77 @@ func (e *Exporter) fuel() (res *model.DnImportRes, err error) {
 	}
 
 	// Create and fetch mongo fuel sales records
-	err = mongo.CreateFuelSales(e.Request)
+	var source []model.StationSales
+	err = e.stage(ctx, StageCompile, func(ctx context.Context) (err error) {
+		source, err = mongo.CreateFuelSales(ctx, e.Request)
+		return err
+	})
 	if err != nil {
-		log.Errorf("Error creating fuel sales: %s", err)
+		e.logger().Errorf("Error creating fuel sales: %s", err)
 		return res, err
 	}
-	sales, err := mongo.FetchExportedFuelSales(e.Request)
+
+	var sales []*model.FuelSalesExport
+	var stations []model.StationNodes
+	err = e.stage(ctx, StageFetch, func(ctx context.Context) (err error) {
+		if sales, err = mongo.FetchExportedFuelSales(ctx, e.Request); err != nil {
+			return err
+		}
+		stations, err = mongo.FetchStationNodes(ctx)
+		return err
+	})
 	if err != nil {
-		log.Errorf("Error fetching fuel sales: %s", err)
+		e.logger().Errorf("Error fetching fuel sales and station nodes: %s", err)
 		return res, err
 	}
 	if len(sales) <= 0 {
-		err = errors.New("Error fetching exported fuel sales")
-		log.Error(err)
+		err = ErrNoFuelSales
+		e.logger().Error(err)
 		return res, err
 	}
 
+	// Report station days without sales, optionally failing or filling with placeholders
+	res.Gaps = findGaps(e.Request, stations, source)
+	if len(res.Gaps) > 0 {
+		e.logger().Warnf("Found %d station days without sales", len(res.Gaps))
+		switch e.Request.GapMode {
+		case model.GapFail:
+			err = ErrMissingDays.Wrap("fuel", fmt.Errorf("%d station days without sales", len(res.Gaps)))
+			e.logger().Error(err)
+			return res, err
+		case model.GapFill:
+			sales = append(sales, gapPlaceholders(res.Gaps, stations, res.ImportTS)...)
+		}
+	}
+
 	res.RecordQuantity = len(sales)
 
-	err = dynamo.CreateFuelSalesRecords(sales, res)
+	// Run data quality checks before writing to dynamo
+	span := e.span.Start("qualityChecks")
+	report := e.Checks.Fuel(sales)
+	span.Annotate("findings", len(report.Findings))
+	span.End(nil)
+	res.Findings = report.Findings
+	if report.Blocked() {
+		err = ErrDataQuality.Wrap("fuel", fmt.Errorf("%d blocking findings", len(report.Blocking())))
+		e.logger().Error(err)
+		if lErr := dynamo.CreateBlockedImportLog(ctx, res); lErr != nil {
+			e.logger().Errorf("Error logging blocked import: %s", lErr)
+		}
+		return res, err
+	}
+
+	err = e.stage(ctx, StageWrite, func(ctx context.Context) error {
+		return dynamo.CreateFuelSalesRecords(ctx, sales, res)
+	})
+	e.progress.ItemsWritten = dynamo.Written()
 	if err != nil {
-		log.Errorf("Error creating dynamo sales records: %s", err)
+		e.logger().Errorf("Error creating dynamo sales records: %s", err)
 		return res, err
 	}
 
+	e.archive(&archive.Batch{Exported: sales, Result: res, Source: source})
+	e.emailFuelSummary(ctx, mongo, sales, stations, res)
+
 	return res, err
 }
@@ -1 +1,65 @@
 package export
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pulpfree/gsales-fs-export/model"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func testStations() []model.StationNodes {
+	st1, _ := primitive.ObjectIDFromHex("56cf1815982d82b0f3000001")
+	st2, _ := primitive.ObjectIDFromHex("56cf1815982d82b0f3000002")
+	nd1, _ := primitive.ObjectIDFromHex("56cf1815982d82b0f3000011")
+	nd2, _ := primitive.ObjectIDFromHex("56cf1815982d82b0f3000012")
+	nd3, _ := primitive.ObjectIDFromHex("56cf1815982d82b0f3000021")
+	return []model.StationNodes{
+		{ID: st1, Name: "Bridge", Nodes: []primitive.ObjectID{nd1, nd2}},
+		{ID: st2, Name: "Thorold", Nodes: []primitive.ObjectID{nd3}},
+	}
+}
+
+func testRequest() *model.Request {
+	st, _ := time.Parse(timeForm, "2023-06-01")
+	en, _ := time.Parse(timeForm, "2023-06-03")
+	return &model.Request{DateStart: st, DateEnd: en, ExportType: model.FuelType}
+}
+
+// TestFindGaps function
+func TestFindGaps(t *testing.T) {
+
+	stations := testStations()
+	req := testRequest()
+	var source []model.StationSales
+	// Bridge has a sale each day, from either of its nodes
+	for d := 0; d < 3; d++ {
+		node := stations[0].Nodes[d%2]
+		source = append(source, model.StationSales{RecordDate: req.DateStart.AddDate(0, 0, d), StationID: node})
+	}
+	source = append(source, model.StationSales{RecordDate: req.DateStart.AddDate(0, 0, 1), StationID: stations[1].Nodes[0]})
+	// a node not mapped to a station does not fill a gap
+	source = append(source, model.StationSales{RecordDate: req.DateStart, StationID: primitive.NewObjectID()})
+
+	gaps := findGaps(req, stations, source)
+	assert.Len(t, gaps, 2)
+	assert.Equal(t, 20230601, gaps[0].Date)
+	assert.Equal(t, 20230603, gaps[1].Date)
+	assert.Equal(t, "Thorold", gaps[1].StationName)
+	assert.Equal(t, stations[1].ID.Hex(), gaps[1].StationID)
+}
+
+// TestGapPlaceholders function
+func TestGapPlaceholders(t *testing.T) {
+
+	stations := testStations()
+	gaps := findGaps(testRequest(), stations[1:], nil)
+
+	sales := gapPlaceholders(gaps, stations, 1685600000)
+	assert.Len(t, sales, 3)
+	assert.True(t, sales[0].Placeholder)
+	assert.Equal(t, stations[1].ID, sales[0].StationID)
+	assert.Equal(t, "20230601-"+stations[1].ID.Hex(), sales[0].ID)
+	assert.Equal(t, 0.0, sales[0].FuelSales.NL)
+}
@@ -0,0 +1,75 @@
+package export
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/pulpfree/gsales-fs-export/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const timeShortForm = "20060102"
+
+// findGaps returns each station and date in the requested range without a source sales document for any of its nodes
+// Gaps come from the sales read for this import, fuel-sales-export can hold docs from earlier imports
+func findGaps(req *model.Request, stations []model.StationNodes, source []model.StationSales) (gaps []*model.Gap) {
+
+	nodeStation := make(map[primitive.ObjectID]string)
+	for _, st := range stations {
+		for _, node := range st.Nodes {
+			nodeStation[node] = st.ID.Hex()
+		}
+	}
+
+	found := make(map[string]bool, len(source))
+	for _, s := range source {
+		station, ok := nodeStation[s.StationID]
+		if !ok {
+			continue
+		}
+		dte, _ := strconv.Atoi(s.RecordDate.Format(timeShortForm))
+		found[gapKey(station, dte)] = true
+	}
+
+	for _, st := range stations {
+		for d := req.DateStart; !d.After(req.DateEnd); d = d.AddDate(0, 0, 1) {
+			dte, _ := strconv.Atoi(d.Format(timeShortForm))
+			if found[gapKey(st.ID.Hex(), dte)] {
+				continue
+			}
+			gaps = append(gaps, &model.Gap{
+				Date:        dte,
+				StationID:   st.ID.Hex(),
+				StationName: st.Name,
+			})
+		}
+	}
+
+	return gaps
+}
+
+// gapPlaceholders creates a zero sales record for each gap
+func gapPlaceholders(gaps []*model.Gap, stations []model.StationNodes, ts int64) (sales []*model.FuelSalesExport) {
+
+	byID := make(map[string]model.StationNodes, len(stations))
+	for _, st := range stations {
+		byID[st.ID.Hex()] = st
+	}
+
+	for _, g := range gaps {
+		sales = append(sales, &model.FuelSalesExport{
+			ID:          fmt.Sprintf("%d-%s", g.Date, g.StationID),
+			FuelSales:   &model.FuelSales{},
+			ImportTS:    ts,
+			Placeholder: true,
+			RecordDate:  g.Date,
+			StationID:   byID[g.StationID].ID,
+		})
+	}
+
+	return sales
+}
+
+func gapKey(stationID string, date int) string {
+	return stationID + "-" + strconv.Itoa(date)
+}
@@ -1,22 +1,21 @@
 package export
 
 import (
-	"errors"
+	"context"
+	"fmt"
 	"time"
 
-	log "github.com/sirupsen/logrus"
-
+	"github.com/pulpfree/gsales-fs-export/archive"
 	"github.com/pulpfree/gsales-fs-export/model"
 	"github.com/pulpfree/gsales-fs-export/model/dynamo"
-	"github.com/pulpfree/gsales-fs-export/model/mongo"
 )
 
-func (e *Exporter) propane() (res *model.DnImportRes, err error) {
+func (e *Exporter) propane(ctx context.Context) (res *model.DnImportRes, err error) {
 
 	// Set MongoDB connection
-	mongo, err := mongo.NewDB(e.cfg.GetMongoConnectURL(), e.cfg.MongoDBName)
+	mongo, err := e.mongoDB(ctx)
 	if err != nil {
-		log.Errorf("Error connecting to mongo: %s", err)
+		e.logger().Errorf("Error connecting to mongo: %s", err)
 		return res, err
 	}
 	defer mongo.Close()
@@ -24,9 +23,12 @@ func (e *Exporter) propane() (res *model.DnImportRes, err error) {
 	// Set DynamoDB connection
 	dynamo, err := dynamo.NewDB(e.cfg.Dynamo)
 	if err != nil {
-		log.Errorf("Error connecting to dynamo: %s", err)
+		e.logger().Errorf("Error connecting to dynamo: %s", err)
 		return res, err
 	}
+	dynamo.Metrics = e.Metrics
+	dynamo.Trace = e.span
+	dynamo.Log = e.Log
 
 	t := time.Now()
 	res = &model.DnImportRes{
@@ -38,29 +40,58 @@ func (e *Exporter) propane() (res *model.DnImportRes, err error) {
 	}
 
 	// Create and fetch mongo fuel sales records
-	err = mongo.CreatePropaneSales(e.Request)
+	var source []model.PropaneSale
+	err = e.stage(ctx, StageCompile, func(ctx context.Context) (err error) {
+		source, err = mongo.CreatePropaneSales(ctx, e.Request)
+		return err
+	})
 	if err != nil {
-		log.Errorf("Error creating propane sales: %s", err)
+		e.logger().Errorf("Error creating propane sales: %s", err)
 		return res, err
 	}
-	sales, err := mongo.FetchExportedPropaneSales(e.Request)
+
+	var sales []*model.PropaneSaleExport
+	err = e.stage(ctx, StageFetch, func(ctx context.Context) (err error) {
+		sales, err = mongo.FetchExportedPropaneSales(ctx, e.Request)
+		return err
+	})
 	if err != nil {
-		log.Errorf("Error fetching propane sales: %s", err)
+		e.logger().Errorf("Error fetching propane sales: %s", err)
 		return res, err
 	}
 	if len(sales) <= 0 {
-		err = errors.New("Error fetching exported propane sales")
-		log.Error(err)
+		err = ErrNoPropaneSales
+		e.logger().Error(err)
 		return res, err
 	}
 
 	res.RecordQuantity = len(sales)
 
-	err = dynamo.CreatePropaneSalesRecords(sales, res)
+	// Run data quality checks before writing to dynamo
+	span := e.span.Start("qualityChecks")
+	report := e.Checks.Propane(sales)
+	span.Annotate("findings", len(report.Findings))
+	span.End(nil)
+	res.Findings = report.Findings
+	if report.Blocked() {
+		err = ErrDataQuality.Wrap("propane", fmt.Errorf("%d blocking findings", len(report.Blocking())))
+		e.logger().Error(err)
+		if lErr := dynamo.CreateBlockedImportLog(ctx, res); lErr != nil {
+			e.logger().Errorf("Error logging blocked import: %s", lErr)
+		}
+		return res, err
+	}
+
+	err = e.stage(ctx, StageWrite, func(ctx context.Context) error {
+		return dynamo.CreatePropaneSalesRecords(ctx, sales, res)
+	})
+	e.progress.ItemsWritten = dynamo.Written()
 	if err != nil {
-		log.Errorf("Error creating dynamo sales records: %s", err)
+		e.logger().Errorf("Error creating dynamo sales records: %s", err)
 		return res, err
 	}
 
+	e.archive(&archive.Batch{Exported: sales, Result: res, Source: source})
+
 	return res, err
 }
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	pres "github.com/pulpfree/lambda-go-proxy-response"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pulpfree/gsales-fs-export/export"
+	"github.com/pulpfree/gsales-fs-export/logging"
+	"github.com/pulpfree/gsales-fs-export/model"
+	"github.com/pulpfree/gsales-fs-export/render"
+	"github.com/pulpfree/gsales-fs-export/validators"
+)
+
+const (
+	dataResource    = "/export/data"
+	xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+// handleData returns previously exported sales in the requested format
+func handleData(ctx context.Context, req events.APIGatewayProxyRequest, hdrs map[string]string, t time.Time, logger *log.Entry) events.APIGatewayProxyResponse {
+
+	reqVars, format, err := validators.DataRequest(req.QueryStringParameters)
+	if err != nil {
+		logger.WithField("params", req.QueryStringParameters).Errorf("err in validators.DataRequest: %s", err)
+		return errorRes(err, hdrs, t)
+	}
+
+	exporter := export.New(reqVars, cfg)
+	exporter.Log = logging.Request(logger, cfg.GetStageEnv(), reqVars)
+
+	// workbooks always include both fuel and propane sheets
+	var data *model.ExportData
+	if format == model.FormatXLSX {
+		data, err = exporter.WorkbookData(ctx)
+	} else {
+		data, err = exporter.Data(ctx)
+	}
+	if err != nil {
+		return errorRes(err, hdrs, t)
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case model.FormatCSV:
+		if err = render.CSV(&buf, data); err != nil {
+			return errorRes(err, hdrs, t)
+		}
+		return events.APIGatewayProxyResponse{
+			Body:       buf.String(),
+			Headers:    fileHeaders(hdrs, "text/csv; charset=utf-8", fileName(reqVars, format)),
+			StatusCode: http.StatusOK,
+		}
+
+	case model.FormatXLSX:
+		if err = render.XLSX(&buf, data); err != nil {
+			return errorRes(err, hdrs, t)
+		}
+		return events.APIGatewayProxyResponse{
+			Body:            base64.StdEncoding.EncodeToString(buf.Bytes()),
+			Headers:         fileHeaders(hdrs, xlsxContentType, fileName(reqVars, format)),
+			IsBase64Encoded: true,
+			StatusCode:      http.StatusOK,
+		}
+	}
+
+	return pres.ProxyRes(pres.Response{
+		Code:      http.StatusOK,
+		Data:      data,
+		Status:    "success",
+		Timestamp: t.Unix(),
+	}, hdrs, nil)
+}
+
+// fileHeaders copies the default headers, setting the content type and attachment name
+func fileHeaders(hdrs map[string]string, contentType, name string) map[string]string {
+
+	h := make(map[string]string, len(hdrs)+2)
+	for k, v := range hdrs {
+		h[k] = v
+	}
+	h["Content-Type"] = contentType
+	h["Content-Disposition"] = fmt.Sprintf("attachment; filename=%q", name)
+	h["Access-Control-Expose-Headers"] = "Content-Disposition"
+
+	return h
+}
+
+// fileName returns a download name such as fuel-2023-06-01-2023-06-30.csv
+func fileName(r *model.Request, format model.Format) string {
+	return fmt.Sprintf("%s-%s-%s.%s", r.ExportType, r.DateStart.Format(timeForm), r.DateEnd.Format(timeForm), format)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	pres "github.com/pulpfree/lambda-go-proxy-response"
+
+	"github.com/pulpfree/gsales-fs-export/model"
+	"github.com/pulpfree/gsales-fs-export/validators"
+)
+
+// kindStatus maps error kinds to http response codes
+var kindStatus = map[model.ErrorKind]int{
+	model.KindInvalid:  http.StatusBadRequest,
+	model.KindNotFound: http.StatusNotFound,
+	model.KindConflict: http.StatusConflict,
+	model.KindUpstream: http.StatusBadGateway,
+	model.KindTimeout:  http.StatusGatewayTimeout,
+}
+
+// errorRes maps validation and typed errors to a response with the matching
+// status code and error codes in the body. Anything else is returned as a 500
+func errorRes(err error, hdrs map[string]string, t time.Time) events.APIGatewayProxyResponse {
+
+	var vErr *validators.ValidationError
+	if errors.As(err, &vErr) {
+		return pres.ProxyRes(pres.Response{
+			Code:      vErr.Status,
+			Data:      vErr.Violations,
+			Message:   vErr.Error(),
+			Status:    "fail",
+			Timestamp: t.Unix(),
+		}, hdrs, nil)
+	}
+
+	var mErr *model.Error
+	if !errors.As(err, &mErr) {
+		return pres.ProxyRes(pres.Response{
+			Timestamp: t.Unix(),
+		}, hdrs, err)
+	}
+
+	code, ok := kindStatus[mErr.Kind]
+	if !ok {
+		code = http.StatusInternalServerError
+	}
+
+	return pres.ProxyRes(pres.Response{
+		Code: code,
+		Data: []*model.ErrorResponse{{
+			Status:  code,
+			Type:    mErr.Code,
+			Message: mErr.Msg,
+		}},
+		Message:   mErr.Msg,
+		Status:    "error",
+		Timestamp: t.Unix(),
+	}, hdrs, nil)
+}
+
+// blockedRes returns the import result, with its findings and gaps, for an export
+// blocked by the quality checks or missing sales days
+func blockedRes(err error, res *model.DnImportRes, hdrs map[string]string, t time.Time) events.APIGatewayProxyResponse {
+
+	return pres.ProxyRes(pres.Response{
+		Code:      http.StatusConflict,
+		Data:      res,
+		Message:   err.Error(),
+		Status:    "fail",
+		Timestamp: t.Unix(),
+	}, hdrs, nil)
+}
+
+// partialRes returns the import result with the progress made by an export stopped before the invocation deadline
+func partialRes(err error, res *model.DnImportRes, hdrs map[string]string, t time.Time) events.APIGatewayProxyResponse {
+
+	return pres.ProxyRes(pres.Response{
+		Code:      http.StatusGatewayTimeout,
+		Data:      res,
+		Message:   err.Error(),
+		Status:    "fail",
+		Timestamp: t.Unix(),
+	}, hdrs, nil)
+}
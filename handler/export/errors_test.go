@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pulpfree/gsales-fs-export/export"
+	"github.com/pulpfree/gsales-fs-export/model/dynamo"
+	"github.com/pulpfree/gsales-fs-export/model/mongo"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestErrorRes function checks typed errors map to their response status
+func TestErrorRes(t *testing.T) {
+
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{export.ErrNoFuelSales, http.StatusNotFound},
+		{mongo.ErrTimeout.Wrap("fetchFuelSales", context.DeadlineExceeded), http.StatusGatewayTimeout},
+		{dynamo.ErrUnavailable.Wrap("PutItem", errors.New("connection refused")), http.StatusBadGateway},
+		{dynamo.ErrConflict.Wrap("PutItem", errors.New("conditional check failed")), http.StatusConflict},
+		{dynamo.ErrStationNotFound.Wrap("CreateFuelSalesRecords", fmt.Errorf("RefStation %s", "abc")), http.StatusInternalServerError},
+		{errors.New("untyped"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		res := errorRes(tt.err, map[string]string{}, time.Now())
+		assert.Equal(t, tt.status, res.StatusCode, tt.err.Error())
+	}
+}
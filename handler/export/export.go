@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"time"
 
 	pres "github.com/pulpfree/lambda-go-proxy-response"
@@ -12,23 +14,18 @@ import (
 
 	"github.com/pulpfree/gsales-fs-export/config"
 	"github.com/pulpfree/gsales-fs-export/export"
-	"github.com/pulpfree/gsales-fs-export/model"
+	"github.com/pulpfree/gsales-fs-export/logging"
 	"github.com/pulpfree/gsales-fs-export/validators"
 )
 
-var cfg *config.Config
+const timeForm = "2006-01-02"
 
-func init() {
-	cfg = &config.Config{}
-	err := cfg.Load()
-	if err != nil {
-		log.Fatal(err)
-	}
-}
+var cfg *config.Config
 
 // HandleRequest function
+// ctx carries the invocation deadline, which the export uses to stop cleanly before Lambda times out
 // NOTE: strange, the error parameter cannot be used or removed... would be good to dig into
-func HandleRequest(req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+func HandleRequest(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 
 	hdrs := make(map[string]string)
 	hdrs["Content-Type"] = "application/json"
@@ -36,15 +33,28 @@ func HandleRequest(req events.APIGatewayProxyRequest) (events.APIGatewayProxyRes
 	hdrs["Access-Control-Allow-Methods"] = "GET,OPTIONS,POST,PUT"
 	hdrs["Access-Control-Allow-Headers"] = "Authorization,Content-Type,X-Amz-Date,Authorization,X-Api-Key,X-Amz-Security-Token"
 
+	// The API Gateway request id ties log lines for the request together
+	correlationID := req.RequestContext.RequestID
+	if correlationID == "" {
+		correlationID = logging.NewID()
+	}
+	logger := logging.Request(logging.New(correlationID), cfg.GetStageEnv(), nil)
+	hdrs["X-Correlation-Id"] = correlationID
+
 	if req.HTTPMethod == "OPTIONS" {
 		return events.APIGatewayProxyResponse{Body: string("null"), Headers: hdrs, StatusCode: 200}, nil
 	}
 
 	t := time.Now()
 
+	// Data downloads
+	if req.HTTPMethod == "GET" && req.Resource == dataResource {
+		return handleData(ctx, req, hdrs, t, logger), nil
+	}
+
 	// If this is a ping test, intercept and return
 	if req.HTTPMethod == "GET" {
-		log.Info("Ping test in handleRequest")
+		logger.Info("Ping test in handleRequest")
 		return pres.ProxyRes(pres.Response{
 			Code:      200,
 			Data:      "pong",
@@ -53,27 +63,35 @@ func HandleRequest(req events.APIGatewayProxyRequest) (events.APIGatewayProxyRes
 		}, hdrs, nil), nil
 	}
 
-	var r *model.RequestInput
-	json.Unmarshal([]byte(req.Body), &r)
-
-	// Validate request params
+	// Decode and validate request params
+	r, err := validators.DecodeRequest(req.Body)
+	if err != nil {
+		logger.WithField("body", req.Body).Errorf("err in validators.DecodeRequest: %s", err)
+		return errorRes(err, hdrs, t), nil
+	}
 	reqVars, err := validators.RequestVars(r)
 	if err != nil {
-		log.Errorf("err in validators.RequestVars: %+v with input of: %+v\n", err, r)
-		return pres.ProxyRes(pres.Response{
-			Timestamp: t.Unix(),
-		}, hdrs, err), nil
+		logger.WithField("input", r).Errorf("err in validators.RequestVars: %s", err)
+		return errorRes(err, hdrs, t), nil
 	}
 
 	// Initialize and process request
 	exporter := export.New(reqVars, cfg)
-	res, err := exporter.Process()
+	exporter.Log = logging.Request(logger, cfg.GetStageEnv(), reqVars)
+	res, err := exporter.Process(ctx)
 	if err != nil {
-		return pres.ProxyRes(pres.Response{
-			Timestamp: t.Unix(),
-		}, hdrs, err), nil
+		if errors.Is(err, export.ErrDataQuality) || errors.Is(err, export.ErrMissingDays) {
+			return blockedRes(err, res, hdrs, t), nil
+		}
+		if errors.Is(err, export.ErrDeadline) && res != nil {
+			return partialRes(err, res, hdrs, t), nil
+		}
+		return errorRes(err, hdrs, t), nil
 	}
-	log.Infof("res in exporter.Process(): %+v\n", res)
+	exporter.Log.WithFields(log.Fields{
+		"importTS":  res.ImportTS,
+		"recordQty": res.RecordQuantity,
+	}).Info("Export complete")
 
 	body, err := json.Marshal(&res)
 	if err != nil {
@@ -90,6 +108,14 @@ func HandleRequest(req events.APIGatewayProxyRequest) (events.APIGatewayProxyRes
 	}, hdrs, nil), nil
 }
 
+// main function loads the config once per cold start, outside init so the package can be tested
 func main() {
+	logging.Setup()
+	cfg = &config.Config{}
+	err := cfg.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	lambda.Start(HandleRequest)
 }
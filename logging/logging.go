@@ -0,0 +1,71 @@
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pulpfree/gsales-fs-export/config"
+	"github.com/pulpfree/gsales-fs-export/model"
+)
+
+// Log field names, kept stable for CloudWatch Insights queries
+const (
+	CorrelationID = "correlationID"
+	DateEnd       = "dateEnd"
+	DateStart     = "dateStart"
+	ExportType    = "exportType"
+	Stage         = "stage"
+	Station       = "station"
+)
+
+const timeForm = "2006-01-02"
+
+// Setup function switches the standard logger to json output
+func Setup() {
+	log.SetFormatter(&log.JSONFormatter{
+		FieldMap: log.FieldMap{
+			log.FieldKeyMsg:  "message",
+			log.FieldKeyTime: "timestamp",
+		},
+	})
+}
+
+// New function returns an entry carrying the correlation id, generating one when id is empty
+func New(id string) *log.Entry {
+	if id == "" {
+		id = NewID()
+	}
+	return log.WithField(CorrelationID, id)
+}
+
+// NewID function returns a random correlation id
+func NewID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Request function adds the stage and request fields to the entry
+func Request(entry *log.Entry, stage config.StageEnvironment, req *model.Request) *log.Entry {
+
+	if entry == nil {
+		entry = log.NewEntry(log.StandardLogger())
+	}
+	fields := log.Fields{Stage: string(stage)}
+	if req != nil {
+		fields[DateEnd] = req.DateEnd.Format(timeForm)
+		fields[DateStart] = req.DateStart.Format(timeForm)
+		fields[ExportType] = string(req.ExportType)
+	}
+	return entry.WithFields(fields)
+}
+
+// Or function returns entry, or an entry on the standard logger when entry is nil
+func Or(entry *log.Entry) *log.Entry {
+	if entry == nil {
+		return log.NewEntry(log.StandardLogger())
+	}
+	return entry
+}
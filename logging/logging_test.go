@@ -0,0 +1,55 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pulpfree/gsales-fs-export/config"
+	"github.com/pulpfree/gsales-fs-export/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRequestFields function
+func TestRequestFields(t *testing.T) {
+
+	var buf bytes.Buffer
+	formatter := log.StandardLogger().Formatter
+	Setup()
+	log.SetOutput(&buf)
+	defer func() {
+		log.SetFormatter(formatter)
+		log.SetOutput(os.Stderr)
+	}()
+
+	req := &model.Request{
+		DateEnd:    time.Date(2023, 6, 7, 0, 0, 0, 0, time.UTC),
+		DateStart:  time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC),
+		ExportType: model.FuelType,
+	}
+	Request(New("c0ffee"), config.TestEnv, req).WithField(Station, "56cf1815982d82b0f3000001").Error("Error upserting fuel sale export")
+
+	line := make(map[string]interface{})
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, "c0ffee", line[CorrelationID])
+	assert.Equal(t, "test", line[Stage])
+	assert.Equal(t, "fuel", line[ExportType])
+	assert.Equal(t, "2023-06-01", line[DateStart])
+	assert.Equal(t, "2023-06-07", line[DateEnd])
+	assert.Equal(t, "56cf1815982d82b0f3000001", line[Station])
+	assert.Equal(t, "Error upserting fuel sale export", line["message"])
+	assert.Equal(t, "error", line["level"])
+	assert.NotEmpty(t, line["timestamp"])
+}
+
+// TestNewGeneratesID function
+func TestNewGeneratesID(t *testing.T) {
+	id := New("").Data[CorrelationID].(string)
+	assert.Len(t, id, 32)
+	assert.NotEqual(t, id, New("").Data[CorrelationID])
+}
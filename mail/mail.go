@@ -0,0 +1,52 @@
+package mail
+
+import (
+	"sync"
+
+	"github.com/pulpfree/gsales-fs-export/config"
+)
+
+// Message struct
+type Message struct {
+	From    string
+	HTML    string
+	Subject string
+	Text    string
+	To      []string
+}
+
+// Mailer interface
+type Mailer interface {
+	Send(m *Message) error
+}
+
+// FromConfig function returns an SMTP mailer when SMTPAddr is set, otherwise SES
+// Returns nil when no recipients are configured
+func FromConfig(cfg *config.Config) (Mailer, error) {
+
+	if len(cfg.MailTo) == 0 {
+		return nil, nil
+	}
+	if cfg.SMTPAddr != "" {
+		return NewSMTP(cfg.SMTPAddr, cfg.SMTPUser, cfg.SMTPPassword), nil
+	}
+	s, err := NewSES(cfg.AWSRegion)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Capture struct keeps sent messages in memory, for tests and local runs
+type Capture struct {
+	Messages []*Message
+	mu       sync.Mutex
+}
+
+// Send method
+func (c *Capture) Send(m *Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Messages = append(c.Messages, m)
+	return nil
+}
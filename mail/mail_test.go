@@ -0,0 +1,76 @@
+package mail
+
+import (
+	"net/smtp"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/ses"
+	"github.com/aws/aws-sdk-go/service/ses/sesiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSES struct {
+	sesiface.SESAPI
+	input *ses.SendEmailInput
+}
+
+func (f *fakeSES) SendEmail(in *ses.SendEmailInput) (*ses.SendEmailOutput, error) {
+	f.input = in
+	return &ses.SendEmailOutput{}, nil
+}
+
+func testMessage() *Message {
+	return &Message{
+		From:    "exports@example.com",
+		HTML:    "<p>Fuel</p>",
+		Subject: "Fuel sales export 2023-06-01 to 2023-06-07",
+		Text:    "Fuel",
+		To:      []string{"a@example.com", "b@example.com"},
+	}
+}
+
+// TestSMTP function
+func TestSMTP(t *testing.T) {
+
+	var gotAddr string
+	var gotAuth smtp.Auth
+	var gotTo []string
+	var gotMsg []byte
+	s := NewSMTP("localhost:2525", "user", "secret")
+	s.send = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		gotAddr, gotAuth, gotTo, gotMsg = addr, a, to, msg
+		return nil
+	}
+
+	require.NoError(t, s.Send(testMessage()))
+	assert.Equal(t, "localhost:2525", gotAddr)
+	assert.NotNil(t, gotAuth)
+	assert.Equal(t, []string{"a@example.com", "b@example.com"}, gotTo)
+
+	msg := string(gotMsg)
+	assert.Contains(t, msg, "To: a@example.com, b@example.com\r\n")
+	assert.Contains(t, msg, "Content-Type: multipart/alternative; boundary=")
+	assert.True(t, strings.Index(msg, "text/plain") < strings.Index(msg, "text/html"))
+	assert.Contains(t, msg, "<p>Fuel</p>")
+}
+
+// TestSES function
+func TestSES(t *testing.T) {
+
+	svc := &fakeSES{}
+	require.NoError(t, (&SES{svc: svc}).Send(testMessage()))
+	assert.Equal(t, "exports@example.com", *svc.input.Source)
+	assert.Len(t, svc.input.Destination.ToAddresses, 2)
+	assert.Equal(t, "<p>Fuel</p>", *svc.input.Message.Body.Html.Data)
+}
+
+// TestCapture function
+func TestCapture(t *testing.T) {
+
+	c := &Capture{}
+	var m Mailer = c
+	require.NoError(t, m.Send(testMessage()))
+	assert.Len(t, c.Messages, 1)
+}
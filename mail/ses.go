@@ -0,0 +1,47 @@
+package mail
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ses"
+	"github.com/aws/aws-sdk-go/service/ses/sesiface"
+)
+
+const charSet = "UTF-8"
+
+// SES struct
+type SES struct {
+	svc sesiface.SESAPI
+}
+
+// NewSES function
+func NewSES(region string) (*SES, error) {
+
+	sess, err := session.NewSession(&aws.Config{
+		Region: aws.String(region),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &SES{svc: ses.New(sess)}, nil
+}
+
+// Send method
+func (s *SES) Send(m *Message) error {
+
+	_, err := s.svc.SendEmail(&ses.SendEmailInput{
+		Destination: &ses.Destination{
+			ToAddresses: aws.StringSlice(m.To),
+		},
+		Message: &ses.Message{
+			Body: &ses.Body{
+				Html: &ses.Content{Charset: aws.String(charSet), Data: aws.String(m.HTML)},
+				Text: &ses.Content{Charset: aws.String(charSet), Data: aws.String(m.Text)},
+			},
+			Subject: &ses.Content{Charset: aws.String(charSet), Data: aws.String(m.Subject)},
+		},
+		Source: aws.String(m.From),
+	})
+	return err
+}
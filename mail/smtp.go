@@ -0,0 +1,82 @@
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+)
+
+// SMTP struct sends mail through an SMTP relay, authenticating when a user is set
+type SMTP struct {
+	Addr     string
+	Password string
+	User     string
+	send     func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// NewSMTP function
+func NewSMTP(addr, user, password string) *SMTP {
+	return &SMTP{Addr: addr, Password: password, User: user, send: smtp.SendMail}
+}
+
+// Send method
+func (s *SMTP) Send(m *Message) error {
+
+	body, err := m.MIME()
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if s.User != "" {
+		host, _, err := net.SplitHostPort(s.Addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", s.User, s.Password, host)
+	}
+
+	return s.send(s.Addr, auth, m.From, m.To, body)
+}
+
+// MIME method returns the message as a multipart/alternative email with text and html parts
+func (m *Message) MIME() ([]byte, error) {
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=UTF-8", m.Text},
+		{"text/html; charset=UTF-8", m.HTML},
+	}
+	for _, p := range parts {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {p.contentType}})
+		if err != nil {
+			return nil, err
+		}
+		if _, err = pw.Write([]byte(p.content)); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", m.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(m.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+	msg.Write(body.Bytes())
+
+	return msg.Bytes(), nil
+}
@@ -0,0 +1,218 @@
+package metrics
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Namespace is the CloudWatch namespace export metrics are published under
+const Namespace = "GalesFuelSalesExport"
+
+// Unit string
+type Unit string
+
+// Unit constants
+const (
+	Count        Unit = "Count"
+	Milliseconds Unit = "Milliseconds"
+	None         Unit = "None"
+)
+
+// maxValues is the CloudWatch limit on values per metric in a single EMF document
+const maxValues = 100
+
+// Logger struct collects metrics and writes them as CloudWatch embedded metric format (EMF) json lines
+// Metrics are grouped by their extra dimensions, each group is written as one line on Flush
+// A nil Logger discards all metrics so packages can record unconditionally
+type Logger struct {
+	dims      map[string]string
+	groups    map[string]*group
+	mu        sync.Mutex
+	namespace string
+	now       func() time.Time
+	order     []string
+	w         io.Writer
+}
+
+type group struct {
+	dims   map[string]string
+	names  []string
+	units  map[string]Unit
+	values map[string][]float64
+}
+
+// New function returns a logger writing to w, dims are set on every metric
+func New(w io.Writer, namespace string, dims map[string]string) *Logger {
+	return &Logger{
+		dims:      dims,
+		groups:    make(map[string]*group),
+		namespace: namespace,
+		now:       time.Now,
+		w:         w,
+	}
+}
+
+// Put method records a value for the metric
+func (l *Logger) Put(name string, value float64, unit Unit) {
+	l.PutWith(nil, name, value, unit)
+}
+
+// PutWith method records a value for the metric with dimensions in addition to the logger's
+func (l *Logger) PutWith(dims map[string]string, name string, value float64, unit Unit) {
+
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	g := l.group(dims)
+	if _, ok := g.units[name]; !ok {
+		g.names = append(g.names, name)
+		g.units[name] = unit
+	}
+	g.values[name] = append(g.values[name], value)
+}
+
+// Add method increments a counter, counters are written as a single summed value
+func (l *Logger) Add(dims map[string]string, name string, n float64) {
+
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	g := l.group(dims)
+	if _, ok := g.units[name]; !ok {
+		g.names = append(g.names, name)
+		g.units[name] = Count
+		g.values[name] = []float64{0}
+	}
+	g.values[name][0] += n
+}
+
+// Since method records the milliseconds elapsed since start
+func (l *Logger) Since(dims map[string]string, name string, start time.Time) {
+	l.PutWith(dims, name, float64(time.Since(start).Milliseconds()), Milliseconds)
+}
+
+// Flush method writes the recorded metrics and resets the logger
+func (l *Logger) Flush() error {
+
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ts := l.now().UnixNano() / int64(time.Millisecond)
+	enc := json.NewEncoder(l.w)
+	for _, key := range l.order {
+		for _, doc := range l.documents(l.groups[key], ts) {
+			if err := enc.Encode(doc); err != nil {
+				return err
+			}
+		}
+	}
+
+	l.groups = make(map[string]*group)
+	l.order = nil
+	return nil
+}
+
+// group returns the group for the dimensions, creating it when needed
+func (l *Logger) group(dims map[string]string) *group {
+
+	key := dimsKey(dims)
+	g, ok := l.groups[key]
+	if !ok {
+		g = &group{dims: dims, units: make(map[string]Unit), values: make(map[string][]float64)}
+		l.groups[key] = g
+		l.order = append(l.order, key)
+	}
+	return g
+}
+
+type metricDef struct {
+	Name string `json:"Name"`
+	Unit Unit   `json:"Unit"`
+}
+
+type directive struct {
+	Dimensions [][]string  `json:"Dimensions"`
+	Metrics    []metricDef `json:"Metrics"`
+	Namespace  string      `json:"Namespace"`
+}
+
+type metadata struct {
+	CloudWatchMetrics []directive `json:"CloudWatchMetrics"`
+	Timestamp         int64       `json:"Timestamp"`
+}
+
+// documents builds the EMF documents for a group, splitting metrics with more values than allowed
+func (l *Logger) documents(g *group, ts int64) []map[string]interface{} {
+
+	dimNames := make([]string, 0, len(l.dims)+len(g.dims))
+	for k := range l.dims {
+		dimNames = append(dimNames, k)
+	}
+	for k := range g.dims {
+		if _, ok := l.dims[k]; !ok {
+			dimNames = append(dimNames, k)
+		}
+	}
+	sort.Strings(dimNames)
+
+	var docs []map[string]interface{}
+	for start := 0; ; start += maxValues {
+		doc := make(map[string]interface{})
+		var defs []metricDef
+		for _, name := range g.names {
+			vals := g.values[name]
+			if start >= len(vals) {
+				continue
+			}
+			end := start + maxValues
+			if end > len(vals) {
+				end = len(vals)
+			}
+			defs = append(defs, metricDef{Name: name, Unit: g.units[name]})
+			if end-start == 1 {
+				doc[name] = vals[start]
+			} else {
+				doc[name] = vals[start:end]
+			}
+		}
+		if len(defs) == 0 {
+			break
+		}
+
+		for k, v := range l.dims {
+			doc[k] = v
+		}
+		for k, v := range g.dims {
+			doc[k] = v
+		}
+		doc["_aws"] = metadata{
+			CloudWatchMetrics: []directive{{Dimensions: [][]string{dimNames}, Metrics: defs, Namespace: l.namespace}},
+			Timestamp:         ts,
+		}
+		docs = append(docs, doc)
+	}
+
+	return docs
+}
+
+func dimsKey(dims map[string]string) string {
+	keys := make([]string, 0, len(dims))
+	for k, v := range dims {
+		keys = append(keys, k+"="+v)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
@@ -0,0 +1,109 @@
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeLines(t *testing.T, out string) (docs []map[string]interface{}) {
+	sc := bufio.NewScanner(strings.NewReader(out))
+	for sc.Scan() {
+		doc := make(map[string]interface{})
+		require.NoError(t, json.Unmarshal(sc.Bytes(), &doc))
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
+// TestFlush function
+func TestFlush(t *testing.T) {
+
+	var buf bytes.Buffer
+	l := New(&buf, Namespace, map[string]string{"Stage": "test", "ExportType": "fuel"})
+	l.now = func() time.Time { return time.Unix(1685836800, 0) }
+
+	l.Put("ExportDuration", 1250, Milliseconds)
+	l.Add(map[string]string{"Table": "GDS_FuelSale"}, "ItemsWritten", 1)
+	l.Add(map[string]string{"Table": "GDS_FuelSale"}, "ItemsWritten", 2)
+	l.PutWith(map[string]string{"Operation": "fetchFuelSales"}, "MongoLatency", 20, Milliseconds)
+	l.PutWith(map[string]string{"Operation": "fetchFuelSales"}, "MongoLatency", 30, Milliseconds)
+	require.NoError(t, l.Flush())
+
+	docs := decodeLines(t, buf.String())
+	require.Len(t, docs, 3)
+
+	assert.Equal(t, 1250.0, docs[0]["ExportDuration"])
+	assert.Equal(t, "test", docs[0]["Stage"])
+	meta := docs[0]["_aws"].(map[string]interface{})
+	assert.Equal(t, 1685836800000.0, meta["Timestamp"])
+	cw := meta["CloudWatchMetrics"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, Namespace, cw["Namespace"])
+	assert.Equal(t, []interface{}{[]interface{}{"ExportType", "Stage"}}, cw["Dimensions"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"Name": "ExportDuration", "Unit": "Milliseconds"}}, cw["Metrics"])
+
+	assert.Equal(t, 3.0, docs[1]["ItemsWritten"])
+	assert.Equal(t, "GDS_FuelSale", docs[1]["Table"])
+	cw = docs[1]["_aws"].(map[string]interface{})["CloudWatchMetrics"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, []interface{}{[]interface{}{"ExportType", "Stage", "Table"}}, cw["Dimensions"])
+
+	assert.Equal(t, []interface{}{20.0, 30.0}, docs[2]["MongoLatency"])
+
+	// flushing resets the logger
+	buf.Reset()
+	require.NoError(t, l.Flush())
+	assert.Empty(t, buf.String())
+}
+
+// TestFlushSplitsValues function
+func TestFlushSplitsValues(t *testing.T) {
+
+	var buf bytes.Buffer
+	l := New(&buf, Namespace, nil)
+	for i := 0; i < maxValues+1; i++ {
+		l.Put("MongoLatency", float64(i), Milliseconds)
+	}
+	require.NoError(t, l.Flush())
+
+	docs := decodeLines(t, buf.String())
+	require.Len(t, docs, 2)
+	assert.Len(t, docs[0]["MongoLatency"], maxValues)
+	assert.Equal(t, float64(maxValues), docs[1]["MongoLatency"])
+}
+
+// TestStdout function captures stdout as the lambda runtime does
+func TestStdout(t *testing.T) {
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	l := New(os.Stdout, Namespace, map[string]string{"Stage": "test"})
+	l.Put("RecordsExported", 42, Count)
+	require.NoError(t, l.Flush())
+	w.Close()
+
+	out, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	docs := decodeLines(t, string(out))
+	require.Len(t, docs, 1)
+	assert.Equal(t, 42.0, docs[0]["RecordsExported"])
+}
+
+// TestNilLogger function
+func TestNilLogger(t *testing.T) {
+	var l *Logger
+	l.Put("ExportDuration", 1, Milliseconds)
+	l.Add(nil, "ItemsWritten", 1)
+	assert.NoError(t, l.Flush())
+}
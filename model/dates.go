@@ -0,0 +1,25 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// YearWeek extracts a yearweek YYYYWW integer from the provided YYYYMMDD date
+//
+// As golang uses the ISO week with Sunday being the last day of the week,
+// this function attempts to use the US/Canada/Australia method with Sunday
+// as the first day of the week
+//
+// It's possible this could create problems at some point in the calendar
+func YearWeek(date int) (yearWeek int) {
+	t, _ := time.Parse("20060102", strconv.Itoa(date))
+	yr, wk := t.ISOWeek()
+	if t.Weekday() == 0 {
+		wk++
+	}
+	yearWeek, _ = strconv.Atoi(fmt.Sprintf("%d%02d", yr, wk))
+
+	return yearWeek
+}
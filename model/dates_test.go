@@ -0,0 +1,24 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestYearWeek function checks weeks are zero padded, so the values sort and match YYYYWW queries
+func TestYearWeek(t *testing.T) {
+
+	tests := []struct {
+		date int
+		want int
+	}{
+		{20230104, 202301},
+		{20230108, 202302}, // Sunday starts the next week
+		{20230301, 202309},
+		{20230315, 202311},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, YearWeek(tt.date), tt.date)
+	}
+}
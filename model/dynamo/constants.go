@@ -1,20 +1,29 @@
 package dynamo
 
-const prefix = "GDS_"
+// DefaultTablePrefix is used when the config has no TablePrefix
+const DefaultTablePrefix = "GDS_"
 
 // Dynamo Table constants
+// These are the unprefixed table names, resolved to the configured name with Dynamo.Table
 const (
-	Dip            = prefix + "Dip"
-	DipOverShort   = prefix + "DipOverShort"
-	FuelDeliver    = prefix + "FuelDeliver"
-	FuelPrice      = prefix + "FuelPrice"
-	FuelSale       = prefix + "FuelSale"
-	FuelSaleWeekly = prefix + "FuelSaleWeekly"
-	ImportLog      = prefix + "ImportLog"
-	PropaneDeliver = prefix + "PropaneDeliver"
-	PropaneSale    = prefix + "PropaneSale"
-	Station        = prefix + "Station"
-	StationNode    = prefix + "StationNode"
-	StationTank    = prefix + "StationTank"
-	Tank           = prefix + "Tank"
+	Dip            = "Dip"
+	DipOverShort   = "DipOverShort"
+	FuelDeliver    = "FuelDeliver"
+	FuelMargin     = "FuelMargin"
+	FuelPrice      = "FuelPrice"
+	FuelSale       = "FuelSale"
+	FuelSaleWeekly = "FuelSaleWeekly"
+	ImportLog      = "ImportLog"
+	PropaneDeliver = "PropaneDeliver"
+	PropaneSale    = "PropaneSale"
+	Station        = "Station"
+	StationNode    = "StationNode"
+	StationTank    = "StationTank"
+	Tank           = "Tank"
 )
+
+// Tables lists every table constant
+var Tables = []string{
+	Dip, DipOverShort, FuelDeliver, FuelMargin, FuelPrice, FuelSale, FuelSaleWeekly,
+	ImportLog, PropaneDeliver, PropaneSale, Station, StationNode, StationTank, Tank,
+}
@@ -1,6 +1,7 @@
 package dynamo
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"time"
@@ -8,48 +9,89 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
 	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
 	"github.com/pulpfree/gsales-fs-export/config"
+	"github.com/pulpfree/gsales-fs-export/logging"
+	"github.com/pulpfree/gsales-fs-export/metrics"
 	"github.com/pulpfree/gsales-fs-export/model"
+	"github.com/pulpfree/gsales-fs-export/tracing"
 )
 
 // Dynamo struct
+// Metrics, when set, records items written, failures and retries per table
+// Trace, when set, is the parent span of each read and write
+// Log, when set, carries the request fields and correlation id
 type Dynamo struct {
-	config *config.Dynamo
-	db     *dynamodb.DynamoDB
+	Log     *log.Entry
+	Metrics *metrics.Logger
+	Trace   *tracing.Span
+	config  *config.Dynamo
+	db      dynamodbiface.DynamoDBAPI
+	tables  map[string]string
+	written int
 }
 
 // NewDB connection function
+// The endpoint, credentials and retry settings are taken from cfg, see sessionConfig
 func NewDB(cfg *config.Dynamo) (*Dynamo, error) {
 
-	var err error
-
-	sess, err := session.NewSession(&aws.Config{
-		Region: aws.String(cfg.Region),
-	})
+	awsCfg, err := sessionConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	tables, err := tableNames(cfg)
 	if err != nil {
 		return nil, err
 	}
+
+	sess, err := session.NewSession(awsCfg)
+	if err != nil {
+		return nil, wrapErr("NewDB", err)
+	}
 	svc := dynamodb.New(sess)
 
-	return &Dynamo{
+	d := &Dynamo{
 		config: cfg,
 		db:     svc,
-	}, err
+		tables: tables,
+	}
+
+	// Record retries made by the sdk for each completed request
+	svc.Handlers.Complete.PushBack(func(r *request.Request) {
+		if r.RetryCount > 0 {
+			d.Metrics.Add(map[string]string{"Operation": r.Operation.Name}, "DynamoRetries", float64(r.RetryCount))
+		}
+	})
+
+	return d, err
 }
 
 // CreateFuelSalesRecords method
-func (d *Dynamo) CreateFuelSalesRecords(sales []*model.FuelSalesExport, res *model.DnImportRes) (err error) {
+func (d *Dynamo) CreateFuelSalesRecords(ctx context.Context, sales []*model.FuelSalesExport, res *model.DnImportRes) (err error) {
+
+	span := d.Trace.Start("CreateFuelSalesRecords")
+	span.Annotate("sales", len(sales))
+	defer func() { span.End(err) }()
 
-	stations, err := d.fetchStations()
+	stations, err := d.fetchStations(ctx)
+	if err != nil {
+		return err
+	}
 
 	for _, sale := range sales {
 
 		stationRef := sale.StationID.Hex()
-		stationID := stations[stationRef].ID
+		l := d.logger().WithFields(log.Fields{logging.Station: stationRef, "date": sale.RecordDate})
+		station, ok := stations[stationRef]
+		if !ok {
+			return ErrStationNotFound.Wrap("CreateFuelSalesRecords", fmt.Errorf("RefStation %s", stationRef))
+		}
+		stationID := station.ID
 
 		fuelSales := &model.FuelSales{
 			NL:   sale.FuelSales.NL,
@@ -59,40 +101,46 @@ func (d *Dynamo) CreateFuelSalesRecords(sales []*model.FuelSalesExport, res *mod
 			PROP: sale.FuelSales.PROP,
 		}
 		item := model.DnFuelSales{
-			AvgFuelCost: sale.AvgFuelCost,
-			Date:        sale.RecordDate,
-			ImportTS:    sale.ImportTS,
-			Sales:       fuelSales,
-			StationID:   stationID,
-			YearWeek:    setYearWeek(sale.RecordDate),
+			AvgFuelCost:      sale.AvgFuelCost,
+			Date:             sale.RecordDate,
+			FuelPrices:       sale.AvgFuelCosts,
+			ImportTS:         sale.ImportTS,
+			Margins:          sale.FuelMargins,
+			Placeholder:      sale.Placeholder,
+			Sales:            fuelSales,
+			StationID:        stationID,
+			UnweightedPrices: sale.UnweightedFuelCosts,
+			YearWeek:         model.YearWeek(sale.RecordDate),
 		}
 
 		av, err := dynamodbattribute.MarshalMap(item)
 		if err != nil {
-			log.Errorf("Error marshalling map: %s", err)
+			l.Errorf("Error marshalling map: %s", err)
 			return err
 		}
 
-		input := &dynamodb.PutItemInput{
-			Item:      av,
-			TableName: aws.String(FuelSale),
+		err = d.putItem(ctx, span, FuelSale, av)
+		if err != nil {
+			l.Errorf("Error calling PutItem: %s", err)
+			return err
 		}
-		_, err = d.db.PutItem(input)
+
+		err = d.createFuelPriceRecord(ctx, span, item)
 		if err != nil {
-			log.Errorf("Error calling PutItem: %s", err)
+			l.Errorf("Error calling createFuelPriceRecord: %s", err)
 			return err
 		}
 
-		err = d.createFuelPriceRecord(item)
+		err = d.createFuelMarginRecord(ctx, span, item)
 		if err != nil {
-			log.Errorf("Error calling createFuelPriceRecord: %s", err)
+			l.Errorf("Error calling createFuelMarginRecord: %s", err)
 			return err
 		}
 	}
 
-	err = d.createImportLog(res)
+	err = d.createImportLog(ctx, res)
 	if err != nil {
-		log.Errorf("Error calling createImportLog: %s", err)
+		d.logger().Errorf("Error calling createImportLog: %s", err)
 		return err
 	}
 
@@ -100,7 +148,11 @@ func (d *Dynamo) CreateFuelSalesRecords(sales []*model.FuelSalesExport, res *mod
 }
 
 // CreatePropaneSalesRecords method
-func (d *Dynamo) CreatePropaneSalesRecords(sales []*model.PropaneSaleExport, res *model.DnImportRes) (err error) {
+func (d *Dynamo) CreatePropaneSalesRecords(ctx context.Context, sales []*model.PropaneSaleExport, res *model.DnImportRes) (err error) {
+
+	span := d.Trace.Start("CreatePropaneSalesRecords")
+	span.Annotate("sales", len(sales))
+	defer func() { span.End(err) }()
 
 	for _, sale := range sales {
 		item := model.DnPropaneSales{
@@ -109,42 +161,54 @@ func (d *Dynamo) CreatePropaneSalesRecords(sales []*model.PropaneSaleExport, res
 			Sales:    sale.Litres,
 			TankID:   sale.TankID,
 			Year:     setYear(sale.RecordDate),
-			YearWeek: setYearWeek(sale.RecordDate),
+			YearWeek: model.YearWeek(sale.RecordDate),
 		}
 
 		av, err := dynamodbattribute.MarshalMap(item)
 		if err != nil {
-			log.Errorf("Error marshalling map: %s", err)
+			d.logger().Errorf("Error marshalling map: %s", err)
 			return err
 		}
 
-		input := &dynamodb.PutItemInput{
-			Item:      av,
-			TableName: aws.String(PropaneSale),
-		}
-		_, err = d.db.PutItem(input)
+		err = d.putItem(ctx, span, PropaneSale, av)
 		if err != nil {
-			log.Errorf("Error calling PutItem: %s", err)
+			d.logger().Errorf("Error calling PutItem: %s", err)
 			return err
 		}
 	}
 
-	err = d.createImportLog(res)
+	err = d.createImportLog(ctx, res)
 	if err != nil {
-		log.Errorf("Error calling createImportLog: %s", err)
+		d.logger().Errorf("Error calling createImportLog: %s", err)
 		return err
 	}
 
 	return err
 }
 
+// CreateBlockedImportLog method records an export stopped by data quality checks, with its findings
+func (d *Dynamo) CreateBlockedImportLog(ctx context.Context, res *model.DnImportRes) (err error) {
+
+	res.Status = model.ImportBlocked
+
+	err = d.createImportLog(ctx, res)
+	if err != nil {
+		d.logger().Errorf("Error calling createImportLog: %s", err)
+	}
+
+	return err
+}
+
 // fetchStations method
-func (d *Dynamo) fetchStations() (stationMap map[string]*model.DnStation, err error) {
+func (d *Dynamo) fetchStations(ctx context.Context) (stationMap map[string]*model.DnStation, err error) {
+
+	span := d.Trace.Start("fetchStations")
+	defer func() { span.End(err) }()
 
 	proj := expression.NamesList(expression.Name("ID"), expression.Name("Name"), expression.Name("RefStation"))
 	expr, err := expression.NewBuilder().WithProjection(proj).Build()
 	if err != nil {
-		log.Errorf("Error building expression: %s", err)
+		d.logger().Errorf("Error building expression: %s", err)
 		return stationMap, err
 	}
 
@@ -152,13 +216,15 @@ func (d *Dynamo) fetchStations() (stationMap map[string]*model.DnStation, err er
 		ExpressionAttributeNames: expr.Names(),
 		FilterExpression:         expr.Filter(),
 		ProjectionExpression:     expr.Projection(),
-		TableName:                aws.String(Station),
+		TableName:                aws.String(d.Table(Station)),
 	}
 
-	result, err := d.db.Scan(params)
+	start := time.Now()
+	result, err := d.db.ScanWithContext(ctx, params)
+	d.Metrics.Since(map[string]string{"Table": d.Table(Station)}, "DynamoScanLatency", start)
 	if err != nil {
-		log.Errorf("Dynamo query API call failed: %s", err)
-		return stationMap, err
+		d.logger().Errorf("Dynamo query API call failed: %s", err)
+		return stationMap, wrapErr("Scan", err)
 	}
 
 	stationMap = make(map[string]*model.DnStation)
@@ -167,7 +233,7 @@ func (d *Dynamo) fetchStations() (stationMap map[string]*model.DnStation, err er
 		item := &model.DnStation{}
 		err := dynamodbattribute.UnmarshalMap(i, &item)
 		if err != nil {
-			log.Errorf("Error unmarshalling: %s", err)
+			d.logger().Errorf("Error unmarshalling: %s", err)
 			return stationMap, err
 		}
 		stationMap[item.RefStation] = item
@@ -177,72 +243,151 @@ func (d *Dynamo) fetchStations() (stationMap map[string]*model.DnStation, err er
 }
 
 // createImportLog method
-func (d *Dynamo) createImportLog(res *model.DnImportRes) (err error) {
+func (d *Dynamo) createImportLog(ctx context.Context, res *model.DnImportRes) (err error) {
+
+	span := d.Trace.Start("createImportLog")
+	defer func() { span.End(err) }()
 
 	av, err := dynamodbattribute.MarshalMap(res)
 	if err != nil {
-		log.Errorf("Error marshalling map: %s", err)
+		d.logger().Errorf("Error marshalling map: %s", err)
 		return err
 	}
 
-	input := &dynamodb.PutItemInput{
-		Item:      av,
-		TableName: aws.String(ImportLog),
-	}
-	_, err = d.db.PutItem(input)
+	err = d.putItem(ctx, span, ImportLog, av)
 	if err != nil {
-		log.Errorf("Error calling PutItem: %s", err)
+		d.logger().Errorf("Error calling PutItem: %s", err)
 		return err
 	}
 
 	return err
 }
 
-// createFuelPriceRecord
-func (d *Dynamo) createFuelPriceRecord(fs model.DnFuelSales) (err error) {
+// createFuelPriceRecord creates an item for each grade with a price
+func (d *Dynamo) createFuelPriceRecord(ctx context.Context, parent *tracing.Span, fs model.DnFuelSales) (err error) {
 
-	item := model.DnFuelPrice{
-		Date:      fs.Date,
-		Price:     fs.AvgFuelCost,
-		StationID: fs.StationID,
-		YearWeek:  fs.YearWeek,
-	}
+	for _, grade := range model.FuelGrades {
+		price := fs.FuelPrices[grade]
+		if price <= 0 {
+			continue
+		}
 
-	av, err := dynamodbattribute.MarshalMap(item)
-	if err != nil {
-		log.Errorf("Error marshalling map: %s", err)
-		return err
-	}
+		item := model.DnFuelPrice{
+			Date:      fs.Date,
+			DateGrade: dateGradeKey(fs.Date, grade),
+			Grade:     grade,
+			Price:     price,
+			StationID: fs.StationID,
+			YearWeek:  fs.YearWeek,
+		}
 
-	input := &dynamodb.PutItemInput{
-		Item:      av,
-		TableName: aws.String(FuelPrice),
+		av, err := dynamodbattribute.MarshalMap(item)
+		if err != nil {
+			d.logger().WithField(logging.Station, fs.StationID).Errorf("Error marshalling map: %s", err)
+			return err
+		}
+
+		err = d.putItem(ctx, parent, FuelPrice, av)
+		if err != nil {
+			d.logger().WithField(logging.Station, fs.StationID).Errorf("Error calling PutItem: %s", err)
+			return err
+		}
 	}
-	_, err = d.db.PutItem(input)
-	if err != nil {
-		log.Errorf("Error calling PutItem: %s", err)
-		return err
+
+	return err
+}
+
+// createFuelMarginRecord creates an item for each grade with litres sold
+func (d *Dynamo) createFuelMarginRecord(ctx context.Context, parent *tracing.Span, fs model.DnFuelSales) (err error) {
+
+	for _, grade := range model.FuelGrades {
+		m, ok := fs.Margins[grade]
+		if !ok {
+			continue
+		}
+
+		item := model.DnFuelMargin{
+			COGS:      m.COGS,
+			Cost:      m.Cost,
+			Date:      fs.Date,
+			DateGrade: dateGradeKey(fs.Date, grade),
+			Grade:     grade,
+			Litres:    m.Litres,
+			Margin:    m.Margin,
+			Revenue:   m.Revenue,
+			StationID: fs.StationID,
+			YearWeek:  fs.YearWeek,
+		}
+
+		av, err := dynamodbattribute.MarshalMap(item)
+		if err != nil {
+			d.logger().WithField(logging.Station, fs.StationID).Errorf("Error marshalling map: %s", err)
+			return err
+		}
+
+		err = d.putItem(ctx, parent, FuelMargin, av)
+		if err != nil {
+			d.logger().WithField(logging.Station, fs.StationID).Errorf("Error calling PutItem: %s", err)
+			return err
+		}
 	}
 
 	return err
 }
 
-// setYearWeek extracts a yearweek YYYYWW integer from the provided date
-//
-// As golang uses the ISO week with Sunday being the last day of the week,
-// this function attempts to use the US/Canada/Australia method with Sunday
-// as the first day of the week
-//
-// It's possible this could create problems at some point in the calendar
-func setYearWeek(date int) (yearWeek int) {
-	t, _ := time.Parse("20060102", strconv.Itoa(date))
-	yr, wk := t.ISOWeek()
-	if t.Weekday() == 0 {
-		wk++
+// dateGradeKey function returns the DateGrade sort key, YYYYMMDD#GRADE, of the FuelPrice and FuelMargin tables
+// Readers select a day's grades with begins_with on YYYYMMDD#
+func dateGradeKey(date int, grade string) string {
+	return fmt.Sprintf("%d#%s", date, grade)
+}
+
+// logger method returns the request logger
+func (d *Dynamo) logger() *log.Entry {
+	return logging.Or(d.Log)
+}
+
+// Written method returns the number of items written, used to report progress when an export stops early
+func (d *Dynamo) Written() int {
+	return d.written
+}
+
+// Table method returns the configured name of a table constant
+func (d *Dynamo) Table(name string) string {
+	if t, ok := d.tables[name]; ok {
+		return t
+	}
+	return DefaultTablePrefix + name
+}
+
+// putItem method writes an item, recording the write or failure against the table
+// table is a table constant, resolved with Table. Each write is a child span of parent,
+// annotated with the items sent and those left unprocessed
+func (d *Dynamo) putItem(ctx context.Context, parent *tracing.Span, table string, av map[string]*dynamodb.AttributeValue) (err error) {
+
+	table = d.Table(table)
+	span := parent.Start("PutItem")
+	span.Annotate("table", table)
+	span.Annotate("items", 1)
+	unprocessed := 0
+	defer func() {
+		span.Annotate("unprocessed", unprocessed)
+		span.End(err)
+	}()
+
+	dims := map[string]string{"Table": table}
+	_, err = d.db.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		Item:      av,
+		TableName: aws.String(table),
+	})
+	if err != nil {
+		unprocessed = 1
+		d.Metrics.Add(dims, "DynamoWriteFailures", 1)
+		return wrapErr("PutItem", err)
 	}
-	yearWeek, _ = strconv.Atoi(fmt.Sprintf("%d%d", yr, wk))
+	d.Metrics.Add(dims, "ItemsWritten", 1)
+	d.written++
 
-	return yearWeek
+	return nil
 }
 
 func setYear(date int) (year int) {
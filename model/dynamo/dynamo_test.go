@@ -0,0 +1,245 @@
+package dynamo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/pulpfree/gsales-fs-export/model"
+	"github.com/pulpfree/gsales-fs-export/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDynamo struct records the items put, by table, and scans the pages given
+type fakeDynamo struct {
+	dynamodbiface.DynamoDBAPI
+	items  map[string][]map[string]*dynamodb.AttributeValue
+	pages  [][]map[string]*dynamodb.AttributeValue
+	putErr error
+}
+
+func (f *fakeDynamo) ScanPagesWithContext(ctx aws.Context, in *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool, opts ...request.Option) error {
+	for i, items := range f.pages {
+		if !fn(&dynamodb.ScanOutput{Items: items}, i == len(f.pages)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+func (f *fakeDynamo) PutItemWithContext(ctx aws.Context, in *dynamodb.PutItemInput, opts ...request.Option) (*dynamodb.PutItemOutput, error) {
+	if f.putErr != nil {
+		return nil, f.putErr
+	}
+	if f.items == nil {
+		f.items = make(map[string][]map[string]*dynamodb.AttributeValue)
+	}
+	table := aws.StringValue(in.TableName)
+	f.items[table] = append(f.items[table], in.Item)
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func newFakeDB() (*Dynamo, *fakeDynamo) {
+	fake := &fakeDynamo{}
+	return &Dynamo{db: fake, tables: map[string]string{}}, fake
+}
+
+// TestCreateBlockedImportLog function
+func TestCreateBlockedImportLog(t *testing.T) {
+
+	d, fake := newFakeDB()
+	res := &model.DnImportRes{
+		Findings:   []*model.Finding{{Date: 20200101, Rule: "negative_volume", Severity: model.SeverityBlock}},
+		ImportTS:   1577836800,
+		ImportType: "fuel",
+	}
+
+	require.NoError(t, d.CreateBlockedImportLog(context.Background(), res))
+	items := fake.items[d.Table(ImportLog)]
+	require.Len(t, items, 1)
+
+	var logged model.DnImportRes
+	require.NoError(t, dynamodbattribute.UnmarshalMap(items[0], &logged))
+	assert.Equal(t, model.ImportBlocked, logged.Status)
+	if assert.Len(t, logged.Findings, 1) {
+		assert.Equal(t, "negative_volume", logged.Findings[0].Rule)
+	}
+	assert.Equal(t, 1, d.Written())
+}
+
+// TestDateGradeKey function
+func TestDateGradeKey(t *testing.T) {
+
+	tests := []struct {
+		date  int
+		grade string
+		want  string
+	}{
+		{20230601, model.GradeNL, "20230601#NL"},
+		{20230601, model.GradeCDSL, "20230601#CDSL"},
+		{20231231, model.GradePROP, "20231231#PROP"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, dateGradeKey(tt.date, tt.grade))
+	}
+}
+
+// TestGradeItems function checks one price item per grade with a price and one margin item per grade sold
+func TestGradeItems(t *testing.T) {
+
+	d, fake := newFakeDB()
+	fs := model.DnFuelSales{
+		Date:       20230601,
+		FuelPrices: map[string]float64{model.GradeNL: 1.1, model.GradeDSL: 1.3, model.GradeSNL: 0},
+		Margins: map[string]*model.FuelMargin{
+			model.GradeNL:   {Cost: 1.1, Litres: 1000},
+			model.GradePROP: {Cost: 0.6, Litres: 200},
+		},
+		StationID: "station-1",
+		YearWeek:  202322,
+	}
+	ctx := context.Background()
+	require.NoError(t, d.createFuelPriceRecord(ctx, nil, fs))
+	require.NoError(t, d.createFuelMarginRecord(ctx, nil, fs))
+
+	var prices []model.DnFuelPrice
+	require.NoError(t, dynamodbattribute.UnmarshalListOfMaps(fake.items[d.Table(FuelPrice)], &prices))
+	if assert.Len(t, prices, 2) {
+		assert.Equal(t, "20230601#NL", prices[0].DateGrade)
+		assert.Equal(t, "20230601#DSL", prices[1].DateGrade)
+		assert.Equal(t, 1.3, prices[1].Price)
+		assert.Equal(t, "station-1", prices[1].StationID)
+	}
+
+	var margins []model.DnFuelMargin
+	require.NoError(t, dynamodbattribute.UnmarshalListOfMaps(fake.items[d.Table(FuelMargin)], &margins))
+	if assert.Len(t, margins, 2) {
+		assert.Equal(t, "20230601#NL", margins[0].DateGrade)
+		assert.Equal(t, "20230601#PROP", margins[1].DateGrade)
+		assert.Equal(t, model.GradePROP, margins[1].Grade)
+	}
+}
+
+// TestMigrateFuelPrices function
+func TestMigrateFuelPrices(t *testing.T) {
+
+	legacy := func(date int, price float64) map[string]*dynamodb.AttributeValue {
+		av, err := dynamodbattribute.MarshalMap(&legacyFuelPrice{Date: date, Price: price, StationID: "station-1", YearWeek: 202322})
+		require.NoError(t, err)
+		return av
+	}
+
+	d, fake := newFakeDB()
+	fake.pages = [][]map[string]*dynamodb.AttributeValue{
+		{legacy(20230601, 1.1), legacy(20230602, 1.2)},
+		{legacy(20230603, 1.3)},
+	}
+	ctx := context.Background()
+
+	copied, err := d.MigrateFuelPrices(ctx, "GDS_FuelPriceByDate", true)
+	require.NoError(t, err)
+	assert.Equal(t, 3, copied)
+	assert.Empty(t, fake.items)
+
+	copied, err = d.MigrateFuelPrices(ctx, "GDS_FuelPriceByDate", false)
+	require.NoError(t, err)
+	assert.Equal(t, 3, copied)
+
+	var prices []model.DnFuelPrice
+	require.NoError(t, dynamodbattribute.UnmarshalListOfMaps(fake.items[d.Table(FuelPrice)], &prices))
+	if assert.Len(t, prices, 3) {
+		assert.Equal(t, "20230603#NL", prices[2].DateGrade)
+		assert.Equal(t, model.GradeNL, prices[2].Grade)
+		assert.Equal(t, 1.3, prices[2].Price)
+	}
+
+	_, err = d.MigrateFuelPrices(ctx, d.Table(FuelPrice), false)
+	assert.Error(t, err)
+}
+
+// TestMigrateYearWeeks function
+func TestMigrateYearWeeks(t *testing.T) {
+
+	item := func(yearWeek string) map[string]*dynamodb.AttributeValue {
+		return map[string]*dynamodb.AttributeValue{
+			"StationID": {S: aws.String("station-1")},
+			"YearWeek":  {N: aws.String(yearWeek)},
+		}
+	}
+
+	d, fake := newFakeDB()
+	fake.pages = [][]map[string]*dynamodb.AttributeValue{{item("20231"), item("202310")}}
+	ctx := context.Background()
+
+	fixed, err := d.MigrateYearWeeks(ctx, true)
+	require.NoError(t, err)
+	assert.Equal(t, len(yearWeekTables), fixed)
+	assert.Empty(t, fake.items)
+
+	fixed, err = d.MigrateYearWeeks(ctx, false)
+	require.NoError(t, err)
+	assert.Equal(t, len(yearWeekTables), fixed)
+	for _, table := range yearWeekTables {
+		if items := fake.items[d.Table(table)]; assert.Len(t, items, 1, table) {
+			assert.Equal(t, "202301", aws.StringValue(items[0]["YearWeek"].N))
+		}
+	}
+}
+
+// readSpans function reads n subsegments sent to the daemon address
+func readSpans(t *testing.T, pc net.PacketConn, n int) (spans []map[string]interface{}) {
+
+	buf := make([]byte, 64*1024)
+	for i := 0; i < n; i++ {
+		pc.SetReadDeadline(time.Now().Add(2 * time.Second))
+		size, _, err := pc.ReadFrom(buf)
+		require.NoError(t, err)
+		parts := strings.SplitN(string(buf[:size]), "\n", 2)
+		require.Len(t, parts, 2)
+		doc := make(map[string]interface{})
+		require.NoError(t, json.Unmarshal([]byte(parts[1]), &doc))
+		spans = append(spans, doc)
+	}
+	return spans
+}
+
+// TestPutItemSpans function checks each write is a child span of the public call, with its item counts
+func TestPutItemSpans(t *testing.T) {
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer pc.Close()
+
+	tr, err := tracing.New(pc.LocalAddr().String(), "1-5759e988-bd862e3fe1be46a994272793", "53995c3f42cd8ad8")
+	require.NoError(t, err)
+	defer tr.Close()
+
+	d, fake := newFakeDB()
+	d.Trace = tr.Start("export")
+	sales := []*model.PropaneSaleExport{{Litres: 120, RecordDate: 20200101, TankID: 1}}
+	require.NoError(t, d.CreatePropaneSalesRecords(context.Background(), sales, &model.DnImportRes{ImportType: "propane"}))
+
+	// PutItem, PutItem under createImportLog, createImportLog, CreatePropaneSalesRecords
+	spans := readSpans(t, pc, 4)
+	put, logPut, importLog, call := spans[0], spans[1], spans[2], spans[3]
+	assert.Equal(t, "PutItem", put["name"])
+	assert.Equal(t, call["id"], put["parent_id"])
+	assert.Equal(t, importLog["id"], logPut["parent_id"])
+	assert.Equal(t, map[string]interface{}{"table": d.Table(PropaneSale), "items": 1.0, "unprocessed": 0.0}, put["annotations"])
+
+	fake.putErr = errors.New("throttled")
+	assert.Error(t, d.CreatePropaneSalesRecords(context.Background(), sales, &model.DnImportRes{ImportType: "propane"}))
+	put = readSpans(t, pc, 1)[0]
+	assert.Equal(t, 1.0, put["annotations"].(map[string]interface{})["unprocessed"])
+	assert.Equal(t, true, put["fault"])
+}
@@ -0,0 +1,47 @@
+package dynamo
+
+import (
+	"errors"
+	"net"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/pulpfree/gsales-fs-export/model"
+)
+
+// Dynamo error sentinels
+// A sales station without a GDS station is a gap in the station mapping, not something a retry can fix
+var (
+	ErrConflict        = &model.Error{Code: "dynamo_conflict", Kind: model.KindConflict, Msg: "Dynamo conditional write failed"}
+	ErrStationNotFound = &model.Error{Code: "station_not_found", Kind: model.KindInternal, Msg: "No GDS station matches the sales station"}
+	ErrTimeout         = &model.Error{Code: "dynamo_timeout", Kind: model.KindTimeout, Msg: "Dynamo operation timed out"}
+	ErrUnavailable     = &model.Error{Code: "dynamo_unavailable", Kind: model.KindUpstream, Msg: "Dynamo operation failed"}
+)
+
+// wrapErr classifies an aws sdk error into one of the package sentinels
+func wrapErr(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrTimeout.Wrap(op, err)
+	}
+
+	var aErr awserr.Error
+	if errors.As(err, &aErr) {
+		switch aErr.Code() {
+		case request.CanceledErrorCode, "RequestTimeout", "RequestTimeoutException":
+			return ErrTimeout.Wrap(op, err)
+		case dynamodb.ErrCodeConditionalCheckFailedException, dynamodb.ErrCodeTransactionConflictException:
+			return ErrConflict.Wrap(op, err)
+		}
+		if oErr := aErr.OrigErr(); oErr != nil && errors.As(oErr, &netErr) && netErr.Timeout() {
+			return ErrTimeout.Wrap(op, err)
+		}
+	}
+
+	return ErrUnavailable.Wrap(op, err)
+}
@@ -0,0 +1,31 @@
+package dynamo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/pulpfree/gsales-fs-export/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestWrapErr function
+func TestWrapErr(t *testing.T) {
+
+	assert.NoError(t, wrapErr("op", nil))
+
+	err := wrapErr("PutItem", awserr.New(request.CanceledErrorCode, "canceled", nil))
+	assert.True(t, errors.Is(err, ErrTimeout))
+
+	err = wrapErr("PutItem", awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "failed", nil))
+	assert.True(t, errors.Is(err, ErrConflict))
+
+	err = wrapErr("Scan", awserr.New(dynamodb.ErrCodeResourceNotFoundException, "no table", nil))
+	assert.True(t, errors.Is(err, ErrUnavailable))
+	assert.False(t, errors.Is(err, ErrTimeout))
+
+	// a missing station mapping is a config gap, not a conflict a caller could resolve
+	assert.Equal(t, model.KindInternal, ErrStationNotFound.Kind)
+}
@@ -0,0 +1,155 @@
+package dynamo
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/pulpfree/gsales-fs-export/model"
+)
+
+// legacyFuelPrice struct is a FuelPrice item from before per grade prices,
+// keyed by StationID and Date and holding the fuel_1 cost
+type legacyFuelPrice struct {
+	Date      int     `json:"Date"`
+	Price     float64 `json:"Price"`
+	StationID string  `json:"StationID"`
+	YearWeek  int     `json:"YearWeek"`
+}
+
+// MigrateFuelPrices method copies the items of a FuelPrice table keyed by StationID and Date into
+// the configured FuelPrice table, keyed by StationID and DateGrade. The old price was the fuel_1 cost,
+// so each item becomes the NL grade. Key schemas cannot be changed in place, so the old items
+// are read from a restored copy of the table, see the README. With dryRun nothing is written.
+// It returns the number of items copied.
+func (d *Dynamo) MigrateFuelPrices(ctx context.Context, from string, dryRun bool) (copied int, err error) {
+
+	span := d.Trace.Start("MigrateFuelPrices")
+	defer func() {
+		span.Annotate("items", copied)
+		span.End(err)
+	}()
+
+	if from == d.Table(FuelPrice) {
+		return 0, fmt.Errorf("Migrating %s onto itself, restore a backup of it to another table first", from)
+	}
+
+	// the callback cannot return an error, so it stops the scan and leaves it in itemErr
+	var itemErr error
+	in := &dynamodb.ScanInput{TableName: aws.String(from)}
+	err = d.db.ScanPagesWithContext(ctx, in, func(page *dynamodb.ScanOutput, last bool) bool {
+		var old []*legacyFuelPrice
+		if itemErr = dynamodbattribute.UnmarshalListOfMaps(page.Items, &old); itemErr != nil {
+			return false
+		}
+		for _, o := range old {
+			if !dryRun {
+				var av map[string]*dynamodb.AttributeValue
+				if av, itemErr = dynamodbattribute.MarshalMap(legacyPriceItem(o)); itemErr != nil {
+					return false
+				}
+				if itemErr = d.putItem(ctx, d.Trace, FuelPrice, av); itemErr != nil {
+					return false
+				}
+			}
+			copied++
+		}
+		return true
+	})
+	if err != nil {
+		return copied, wrapErr("Scan", err)
+	}
+	err = itemErr
+
+	return copied, err
+}
+
+// legacyPriceItem function returns the per grade item for a legacy fuel_1 price
+func legacyPriceItem(o *legacyFuelPrice) model.DnFuelPrice {
+	return model.DnFuelPrice{
+		Date:      o.Date,
+		DateGrade: dateGradeKey(o.Date, model.GradeNL),
+		Grade:     model.GradeNL,
+		Price:     o.Price,
+		StationID: o.StationID,
+		YearWeek:  o.YearWeek,
+	}
+}
+
+// yearWeekTables lists the tables whose items carry a YearWeek written by the export
+var yearWeekTables = []string{FuelMargin, FuelPrice, FuelSale, PropaneSale}
+
+// MigrateYearWeeks method rewrites the YearWeek of items written before weeks were zero padded,
+// so 20231 becomes 202301 and the items are found again in the YearWeekIndex.
+// With dryRun nothing is written. It returns the number of items fixed.
+func (d *Dynamo) MigrateYearWeeks(ctx context.Context, dryRun bool) (fixed int, err error) {
+
+	span := d.Trace.Start("MigrateYearWeeks")
+	defer func() {
+		span.Annotate("items", fixed)
+		span.End(err)
+	}()
+
+	for _, table := range yearWeekTables {
+		n, err := d.migrateYearWeeks(ctx, table, dryRun)
+		fixed += n
+		if err != nil {
+			return fixed, err
+		}
+	}
+
+	return fixed, nil
+}
+
+// migrateYearWeeks method rewrites the unpadded YearWeek values of one table
+func (d *Dynamo) migrateYearWeeks(ctx context.Context, table string, dryRun bool) (fixed int, err error) {
+
+	// an unpadded week has five digits, a padded one six
+	in := &dynamodb.ScanInput{
+		TableName:        aws.String(d.Table(table)),
+		FilterExpression: aws.String("YearWeek < :padded"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":padded": {N: aws.String("100000")},
+		},
+	}
+
+	var itemErr error
+	err = d.db.ScanPagesWithContext(ctx, in, func(page *dynamodb.ScanOutput, last bool) bool {
+		for _, av := range page.Items {
+			var item struct {
+				YearWeek int `json:"YearWeek"`
+			}
+			if itemErr = dynamodbattribute.UnmarshalMap(av, &item); itemErr != nil {
+				return false
+			}
+			if item.YearWeek >= 100000 {
+				continue
+			}
+			if !dryRun {
+				padded := make(map[string]*dynamodb.AttributeValue, len(av))
+				for k, v := range av {
+					padded[k] = v
+				}
+				padded["YearWeek"] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(paddedYearWeek(item.YearWeek)))}
+				if itemErr = d.putItem(ctx, d.Trace, table, padded); itemErr != nil {
+					return false
+				}
+			}
+			fixed++
+		}
+		return true
+	})
+	if err != nil {
+		return fixed, wrapErr("Scan", err)
+	}
+
+	return fixed, itemErr
+}
+
+// paddedYearWeek function returns the YYYYWW form of an unpadded YYYYW year week
+func paddedYearWeek(yearWeek int) int {
+	return yearWeek/10*100 + yearWeek%10
+}
@@ -0,0 +1,65 @@
+package dynamo
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/pulpfree/gsales-fs-export/config"
+)
+
+// apiVersion is the only DynamoDB api version the sdk client speaks
+const apiVersion = "2012-08-10"
+
+// defaultMaxRetries matches the sdk's DynamoDB default, used when only retry delays are configured
+const defaultMaxRetries = 10
+
+// Credentials sources
+const (
+	credentialsProfile = "profile"
+	credentialsStatic  = "static"
+)
+
+// sessionConfig function builds the aws config for the DynamoDB client from the config values
+func sessionConfig(cfg *config.Dynamo) (*aws.Config, error) {
+
+	if cfg.APIVersion != "" && cfg.APIVersion != apiVersion {
+		return nil, fmt.Errorf("Unsupported Dynamo APIVersion %s, the client uses %s", cfg.APIVersion, apiVersion)
+	}
+
+	awsCfg := &aws.Config{
+		Region: aws.String(cfg.Region),
+	}
+	if cfg.Endpoint != "" {
+		awsCfg.Endpoint = aws.String(cfg.Endpoint)
+	}
+
+	switch cfg.Credentials {
+	case "":
+	case credentialsStatic:
+		if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+			return nil, fmt.Errorf("Dynamo static credentials require AccessKeyID and SecretAccessKey")
+		}
+		awsCfg.Credentials = credentials.NewStaticCredentials(cfg.AccessKeyID, cfg.SecretAccessKey, "")
+	case credentialsProfile:
+		awsCfg.Credentials = credentials.NewSharedCredentials("", cfg.Profile)
+	default:
+		return nil, fmt.Errorf("Invalid Dynamo Credentials requested: %s", cfg.Credentials)
+	}
+
+	if cfg.MaxRetries > 0 || cfg.MinRetryDelay > 0 || cfg.MaxRetryDelay > 0 {
+		retryer := client.DefaultRetryer{
+			NumMaxRetries: cfg.MaxRetries,
+			MaxRetryDelay: cfg.MaxRetryDelay,
+			MinRetryDelay: cfg.MinRetryDelay,
+		}
+		if retryer.NumMaxRetries == 0 {
+			retryer.NumMaxRetries = defaultMaxRetries
+		}
+		awsCfg = request.WithRetryer(awsCfg, retryer)
+	}
+
+	return awsCfg, nil
+}
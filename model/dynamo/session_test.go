@@ -0,0 +1,65 @@
+package dynamo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/pulpfree/gsales-fs-export/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSessionConfig function
+func TestSessionConfig(t *testing.T) {
+
+	cfg, err := sessionConfig(&config.Dynamo{
+		APIVersion:      "2012-08-10",
+		AccessKeyID:     "local",
+		Credentials:     "static",
+		Endpoint:        "http://localhost:8000",
+		MaxRetries:      3,
+		MinRetryDelay:   10 * time.Millisecond,
+		Region:          "ca-central-1",
+		SecretAccessKey: "local",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "http://localhost:8000", aws.StringValue(cfg.Endpoint))
+	assert.Equal(t, "ca-central-1", aws.StringValue(cfg.Region))
+
+	creds, err := cfg.Credentials.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, "local", creds.AccessKeyID)
+
+	retryer, ok := cfg.Retryer.(client.DefaultRetryer)
+	assert.True(t, ok)
+	assert.Equal(t, 3, retryer.NumMaxRetries)
+	assert.Equal(t, 10*time.Millisecond, retryer.MinRetryDelay)
+}
+
+// TestSessionConfigDefaults function
+func TestSessionConfigDefaults(t *testing.T) {
+
+	cfg, err := sessionConfig(&config.Dynamo{Region: "ca-central-1"})
+	assert.NoError(t, err)
+	assert.Nil(t, cfg.Endpoint)
+	assert.Nil(t, cfg.Credentials)
+	assert.Nil(t, cfg.Retryer)
+
+	cfg, err = sessionConfig(&config.Dynamo{Region: "ca-central-1", MaxRetryDelay: time.Second})
+	assert.NoError(t, err)
+	assert.Equal(t, defaultMaxRetries, cfg.Retryer.(client.DefaultRetryer).NumMaxRetries)
+}
+
+// TestSessionConfigErrors function
+func TestSessionConfigErrors(t *testing.T) {
+
+	_, err := sessionConfig(&config.Dynamo{APIVersion: "2011-12-05"})
+	assert.EqualError(t, err, "Unsupported Dynamo APIVersion 2011-12-05, the client uses 2012-08-10")
+
+	_, err = sessionConfig(&config.Dynamo{Credentials: "static", AccessKeyID: "local"})
+	assert.EqualError(t, err, "Dynamo static credentials require AccessKeyID and SecretAccessKey")
+
+	_, err = sessionConfig(&config.Dynamo{Credentials: "instance"})
+	assert.EqualError(t, err, "Invalid Dynamo Credentials requested: instance")
+}
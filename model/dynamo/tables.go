@@ -0,0 +1,162 @@
+package dynamo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/pulpfree/gsales-fs-export/config"
+)
+
+// tableNames function resolves every table constant to its configured name
+// Names are the TablePrefix, or DefaultTablePrefix when empty, followed by the table unless Tables overrides it.
+// Tables keys match the constants case insensitively, unknown keys are an error.
+func tableNames(cfg *config.Dynamo) (map[string]string, error) {
+
+	prefix := cfg.TablePrefix
+	if prefix == "" {
+		prefix = DefaultTablePrefix
+	}
+
+	names := make(map[string]string, len(Tables))
+	for _, t := range Tables {
+		names[t] = prefix + t
+	}
+
+	for key, name := range cfg.Tables {
+		var found bool
+		for _, t := range Tables {
+			if strings.EqualFold(key, t) {
+				names[t] = name
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("Unknown Dynamo table %s in config Tables", key)
+		}
+	}
+
+	return names, nil
+}
+
+// tableDef struct describes a table's keys and local indexes
+// hash and rng are attribute names, with their types in attrs
+type tableDef struct {
+	attrs   map[string]string
+	hash    string
+	indexes []*indexDef
+	name    string
+	rng     string
+}
+
+// indexDef struct is a local secondary index sharing the table's hash key
+type indexDef struct {
+	name string
+	rng  string
+}
+
+// tableDefs lists every table with the keys the export and its readers rely on
+// Tables not written by this service are included so a local DynamoDB has the complete set
+var tableDefs = []*tableDef{
+	{name: Dip, hash: "StationTankID", rng: "Date", attrs: map[string]string{"StationTankID": "S", "Date": "N"}},
+	{name: DipOverShort, hash: "StationID", rng: "Date", attrs: map[string]string{"StationID": "S", "Date": "N"}},
+	{name: FuelDeliver, hash: "StationID", rng: "Date", attrs: map[string]string{"StationID": "S", "Date": "N"}},
+	{
+		name: FuelMargin, hash: "StationID", rng: "DateGrade",
+		attrs:   map[string]string{"StationID": "S", "DateGrade": "S", "YearWeek": "N"},
+		indexes: []*indexDef{{name: "YearWeekIndex", rng: "YearWeek"}},
+	},
+	{
+		name: FuelPrice, hash: "StationID", rng: "DateGrade",
+		attrs:   map[string]string{"StationID": "S", "DateGrade": "S", "YearWeek": "N"},
+		indexes: []*indexDef{{name: "YearWeekIndex", rng: "YearWeek"}},
+	},
+	{
+		name: FuelSale, hash: "StationID", rng: "Date",
+		attrs:   map[string]string{"StationID": "S", "Date": "N", "YearWeek": "N"},
+		indexes: []*indexDef{{name: "YearWeekIndex", rng: "YearWeek"}},
+	},
+	{name: FuelSaleWeekly, hash: "StationID", rng: "YearWeek", attrs: map[string]string{"StationID": "S", "YearWeek": "N"}},
+	{name: ImportLog, hash: "ImportType", rng: "ImportTS", attrs: map[string]string{"ImportType": "S", "ImportTS": "N"}},
+	{name: PropaneDeliver, hash: "TankID", rng: "Date", attrs: map[string]string{"TankID": "N", "Date": "N"}},
+	{
+		name: PropaneSale, hash: "TankID", rng: "Date",
+		attrs:   map[string]string{"TankID": "N", "Date": "N", "YearWeek": "N"},
+		indexes: []*indexDef{{name: "YearWeekIndex", rng: "YearWeek"}},
+	},
+	{name: Station, hash: "ID", attrs: map[string]string{"ID": "S"}},
+	{name: StationNode, hash: "ID", attrs: map[string]string{"ID": "S"}},
+	{name: StationTank, hash: "ID", attrs: map[string]string{"ID": "S"}},
+	{name: Tank, hash: "ID", attrs: map[string]string{"ID": "S"}},
+}
+
+// input method returns the CreateTable input for the definition under the given table name, billed per request
+func (t *tableDef) input(tableName string) *dynamodb.CreateTableInput {
+
+	in := &dynamodb.CreateTableInput{
+		BillingMode: aws.String(dynamodb.BillingModePayPerRequest),
+		KeySchema:   keySchema(t.hash, t.rng),
+		TableName:   aws.String(tableName),
+	}
+	for _, name := range sortedKeys(t.attrs) {
+		in.AttributeDefinitions = append(in.AttributeDefinitions, &dynamodb.AttributeDefinition{
+			AttributeName: aws.String(name),
+			AttributeType: aws.String(t.attrs[name]),
+		})
+	}
+	for _, idx := range t.indexes {
+		in.LocalSecondaryIndexes = append(in.LocalSecondaryIndexes, &dynamodb.LocalSecondaryIndex{
+			IndexName:  aws.String(idx.name),
+			KeySchema:  keySchema(t.hash, idx.rng),
+			Projection: &dynamodb.Projection{ProjectionType: aws.String(dynamodb.ProjectionTypeAll)},
+		})
+	}
+	return in
+}
+
+// CreateTables method creates each GDS table that does not already exist and waits for it to become active
+// It returns the names of the tables created
+func (d *Dynamo) CreateTables(ctx context.Context) (created []string, err error) {
+
+	for _, t := range tableDefs {
+		name := d.Table(t.name)
+		_, err = d.db.CreateTableWithContext(ctx, t.input(name))
+		if aErr, ok := err.(awserr.Error); ok && aErr.Code() == dynamodb.ErrCodeResourceInUseException {
+			d.logger().Debugf("Table %s exists", name)
+			continue
+		}
+		if err != nil {
+			return created, wrapErr("CreateTable", err)
+		}
+		if err = d.db.WaitUntilTableExistsWithContext(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(name)}); err != nil {
+			return created, wrapErr("WaitUntilTableExists", err)
+		}
+		created = append(created, name)
+	}
+
+	return created, nil
+}
+
+func keySchema(hash, rng string) []*dynamodb.KeySchemaElement {
+	ks := []*dynamodb.KeySchemaElement{
+		{AttributeName: aws.String(hash), KeyType: aws.String(dynamodb.KeyTypeHash)},
+	}
+	if rng != "" {
+		ks = append(ks, &dynamodb.KeySchemaElement{AttributeName: aws.String(rng), KeyType: aws.String(dynamodb.KeyTypeRange)})
+	}
+	return ks
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
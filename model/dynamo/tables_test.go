@@ -0,0 +1,104 @@
+package dynamo
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/pulpfree/gsales-fs-export/config"
+	"github.com/pulpfree/gsales-fs-export/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTableDefs function checks every table constant has a definition with typed keys
+func TestTableDefs(t *testing.T) {
+
+	names := Tables
+	defs := make(map[string]*tableDef)
+	for _, td := range tableDefs {
+		defs[td.name] = td
+	}
+	assert.Len(t, defs, len(names))
+
+	for _, name := range names {
+		td, ok := defs[name]
+		if !assert.True(t, ok, name) {
+			continue
+		}
+		in := td.input("test_" + name)
+		assert.Equal(t, "test_"+name, aws.StringValue(in.TableName))
+		assert.NoError(t, in.Validate(), name)
+
+		// every key attribute is defined and every defined attribute is a key
+		keys := map[string]bool{td.hash: true}
+		if td.rng != "" {
+			keys[td.rng] = true
+		}
+		for _, idx := range td.indexes {
+			keys[idx.rng] = true
+		}
+		assert.Len(t, in.AttributeDefinitions, len(keys), name)
+		for _, ad := range in.AttributeDefinitions {
+			assert.True(t, keys[aws.StringValue(ad.AttributeName)], name)
+		}
+	}
+
+	assert.Equal(t, "DateGrade", defs[FuelPrice].rng)
+	assert.Equal(t, "DateGrade", defs[FuelMargin].rng)
+}
+
+// TestTableDefsMatchItems function checks the items written carry each table's keys
+func TestTableDefsMatchItems(t *testing.T) {
+
+	items := map[string]interface{}{
+		FuelMargin:  model.DnFuelMargin{Date: 20200901, DateGrade: "20200901#NL", StationID: "s1", YearWeek: 202036},
+		FuelPrice:   model.DnFuelPrice{Date: 20200901, DateGrade: "20200901#NL", StationID: "s1", YearWeek: 202036},
+		FuelSale:    model.DnFuelSales{Date: 20200901, StationID: "s1", YearWeek: 202036},
+		ImportLog:   model.DnImportRes{ImportTS: 1600000000, ImportType: "fuel"},
+		PropaneSale: model.DnPropaneSales{Date: 20200901, TankID: 1, YearWeek: 202036},
+	}
+
+	for _, td := range tableDefs {
+		item, ok := items[td.name]
+		if !ok {
+			continue
+		}
+		av, err := dynamodbattribute.MarshalMap(item)
+		assert.NoError(t, err)
+		for attr, typ := range td.attrs {
+			v, ok := av[attr]
+			if !assert.True(t, ok, "%s missing %s", td.name, attr) {
+				continue
+			}
+			if typ == "N" {
+				assert.NotNil(t, v.N, "%s %s", td.name, attr)
+			} else {
+				assert.NotNil(t, v.S, "%s %s", td.name, attr)
+			}
+		}
+	}
+}
+
+// TestTableNames function
+func TestTableNames(t *testing.T) {
+
+	names, err := tableNames(&config.Dynamo{})
+	assert.NoError(t, err)
+	assert.Len(t, names, len(Tables))
+	assert.Equal(t, "GDS_FuelSale", names[FuelSale])
+
+	names, err = tableNames(&config.Dynamo{
+		TablePrefix: "GDS_stage_",
+		Tables:      map[string]string{"importlog": "tmp_1600000000_ImportLog"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "GDS_stage_FuelSale", names[FuelSale])
+	assert.Equal(t, "tmp_1600000000_ImportLog", names[ImportLog])
+
+	_, err = tableNames(&config.Dynamo{Tables: map[string]string{"FuelSales": "x"}})
+	assert.EqualError(t, err, "Unknown Dynamo table FuelSales in config Tables")
+
+	d := &Dynamo{tables: names}
+	assert.Equal(t, "GDS_stage_Station", d.Table(Station))
+	assert.Equal(t, "GDS_FuelSale", (&Dynamo{}).Table(FuelSale))
+}
@@ -0,0 +1,61 @@
+package model
+
+import "fmt"
+
+// ErrorKind int classifies an Error so it can be mapped to a response status
+type ErrorKind int
+
+// Error kind constants
+const (
+	KindInternal ErrorKind = iota
+	KindInvalid
+	KindNotFound
+	KindConflict
+	KindUpstream
+	KindTimeout
+)
+
+// Error struct is the typed error returned by the export, mongo and dynamo packages
+// Code is a stable, machine-readable value that clients can rely on
+type Error struct {
+	Code string
+	Kind ErrorKind
+	Msg  string
+	Op   string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Msg
+	}
+	if e.Op == "" {
+		return fmt.Sprintf("%s: %s", e.Msg, e.Err)
+	}
+	return fmt.Sprintf("%s: %s: %s", e.Msg, e.Op, e.Err)
+}
+
+// Unwrap method
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is method matches errors by Code, allowing errors.Is against package sentinels
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+	return e.Code == t.Code
+}
+
+// Wrap method returns a copy of the error with the operation and cause set
+func (e *Error) Wrap(op string, err error) *Error {
+	return &Error{
+		Code: e.Code,
+		Kind: e.Kind,
+		Msg:  e.Msg,
+		Op:   op,
+		Err:  err,
+	}
+}
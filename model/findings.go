@@ -0,0 +1,22 @@
+package model
+
+// Severity string
+type Severity string
+
+// Severity constants
+const (
+	SeverityWarn  Severity = "warn"
+	SeverityBlock Severity = "block"
+)
+
+// Finding struct is a data quality issue found in exported sales
+type Finding struct {
+	Date      int      `json:"Date" bson:"date"`
+	Grade     string   `json:"Grade,omitempty" bson:"grade,omitempty"`
+	Message   string   `json:"Message" bson:"message"`
+	Rule      string   `json:"Rule" bson:"rule"`
+	Severity  Severity `json:"Severity" bson:"severity"`
+	StationID string   `json:"StationID,omitempty" bson:"stationID,omitempty"`
+	TankID    int      `json:"TankID,omitempty" bson:"tankID,omitempty"`
+	Value     float64  `json:"Value" bson:"value"`
+}
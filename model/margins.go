@@ -0,0 +1,61 @@
+package model
+
+// FuelMargin struct holds the cost of goods, revenue and gross margin for a grade
+type FuelMargin struct {
+	COGS    float64 `bson:"cogs" json:"COGS"`
+	Cost    float64 `bson:"cost" json:"Cost"`
+	Litres  float64 `bson:"litres" json:"Litres"`
+	Margin  float64 `bson:"margin" json:"Margin"`
+	Revenue float64 `bson:"revenue" json:"Revenue"`
+}
+
+// Grade method returns the value for the requested grade
+func (fs *FuelSales) Grade(grade string) float64 {
+	if fs == nil {
+		return 0
+	}
+	switch grade {
+	case GradeNL:
+		return fs.NL
+	case GradeSNL:
+		return fs.SNL
+	case GradeDSL:
+		return fs.DSL
+	case GradeCDSL:
+		return fs.CDSL
+	case GradePROP:
+		return fs.PROP
+	}
+	return 0
+}
+
+// Grade method returns the dollars for the requested grade
+func (fr *FuelRevenue) Grade(grade string) float64 {
+	return (*FuelSales)(fr).Grade(grade)
+}
+
+// ComputeMargins function calculates cost of goods (litres * cost), revenue and
+// gross margin for every grade with litres sold
+func ComputeMargins(sales *FuelSales, revenue *FuelRevenue, costs map[string]float64) map[string]*FuelMargin {
+
+	margins := make(map[string]*FuelMargin)
+	for _, grade := range FuelGrades {
+		litres := sales.Grade(grade)
+		if litres == 0 {
+			continue
+		}
+		cost := costs[grade]
+		cogs := litres * cost
+		rev := revenue.Grade(grade)
+
+		margins[grade] = &FuelMargin{
+			COGS:    cogs,
+			Cost:    cost,
+			Litres:  litres,
+			Margin:  rev - cogs,
+			Revenue: rev,
+		}
+	}
+
+	return margins
+}
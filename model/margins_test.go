@@ -0,0 +1,27 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestComputeMargins function
+func TestComputeMargins(t *testing.T) {
+
+	sales := &FuelSales{NL: 1000, DSL: 200}
+	revenue := &FuelRevenue{NL: 1500, DSL: 330}
+	costs := map[string]float64{GradeNL: 1.10, GradeDSL: 1.35, GradeSNL: 1.25}
+
+	margins := ComputeMargins(sales, revenue, costs)
+
+	assert.Len(t, margins, 2)
+	assert.InDelta(t, 1100.0, margins[GradeNL].COGS, 0.0001)
+	assert.InDelta(t, 400.0, margins[GradeNL].Margin, 0.0001)
+	assert.InDelta(t, 270.0, margins[GradeDSL].COGS, 0.0001)
+	assert.InDelta(t, 60.0, margins[GradeDSL].Margin, 0.0001)
+
+	// missing revenue counts as zero
+	margins = ComputeMargins(sales, nil, costs)
+	assert.InDelta(t, -1100.0, margins[GradeNL].Margin, 0.0001)
+}
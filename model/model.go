@@ -13,11 +13,42 @@ const (
 	PropaneType ExportType = "propane"
 )
 
+// Fuel grade constants, these match the FuelSales field names
+const (
+	GradeNL   = "NL"
+	GradeSNL  = "SNL"
+	GradeDSL  = "DSL"
+	GradeCDSL = "CDSL"
+	GradePROP = "PROP"
+)
+
+// FuelGrades lists every exported fuel grade
+var FuelGrades = []string{GradeNL, GradeSNL, GradeDSL, GradeCDSL, GradePROP}
+
+// GapMode string sets how fuel exports handle station days without sales
+type GapMode string
+
+// Gap mode constants
+const (
+	GapReport GapMode = "report"
+	GapFail   GapMode = "fail"
+	GapFill   GapMode = "fill"
+)
+
+// ImportStatus string is set in the import log for exports that did not write their sales
+type ImportStatus string
+
+// Import status constants
+const (
+	ImportBlocked ImportStatus = "blocked"
+)
+
 // RequestInput struct
 type RequestInput struct {
 	ExportType string `json:"exportType"`
 	DateEnd    string `json:"dateEnd"`
 	DateStart  string `json:"dateStart"`
+	Gaps       string `json:"gaps"`
 }
 
 // Request struct
@@ -25,10 +56,46 @@ type Request struct {
 	DateEnd    time.Time
 	DateStart  time.Time
 	ExportType ExportType
+	GapMode    GapMode
+}
+
+// Format string
+type Format string
+
+// Format constants
+const (
+	FormatJSON Format = "json"
+	FormatCSV  Format = "csv"
+	FormatXLSX Format = "xlsx"
+)
+
+// ExportData struct holds previously exported sales for download
+// Stations maps the station ID hex to the station name
+type ExportData struct {
+	ExportType ExportType           `json:"exportType"`
+	Fuel       []*FuelSalesExport   `json:"fuel,omitempty"`
+	Propane    []*PropaneSaleExport `json:"propane,omitempty"`
+	Stations   map[string]string    `json:"stations,omitempty"`
+}
+
+// Gap struct is a station day within the requested range without a sales document
+type Gap struct {
+	Date        int    `json:"Date" bson:"date"`
+	StationID   string `json:"StationID" bson:"stationID"`
+	StationName string `json:"StationName" bson:"stationName"`
+}
+
+// Progress struct records how far an export got when it stopped before the invocation deadline
+// Completed lists the finished stages in order, Stopped is the stage that ran out of time
+type Progress struct {
+	Completed    []string `json:"Completed"`
+	ItemsWritten int      `json:"ItemsWritten"`
+	Stopped      string   `json:"Stopped"`
 }
 
 // ErrorResponse struct
 type ErrorResponse struct {
+	Field   string `json:"field,omitempty"`
 	Status  int    `json:"status"`
 	Type    string `json:"type"`
 	Message string `json:"message"`
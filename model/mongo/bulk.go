@@ -0,0 +1,91 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// defaultBatchSize is the number of documents sent per bulk write when MDB BatchSize is not set
+const defaultBatchSize = 500
+
+// batchSize method returns the configured batch size, or defaultBatchSize
+func (db *MDB) batchSize() int {
+	if db.BatchSize > 0 {
+		return db.BatchSize
+	}
+	return defaultBatchSize
+}
+
+// insertMany method inserts docs unordered, a batch at a time
+func (db *MDB) insertMany(ctx context.Context, col *mongo.Collection, docs []interface{}) error {
+
+	opts := options.InsertMany().SetOrdered(false)
+
+	return writeBatches(len(docs), db.batchSize(), func(start, end int) error {
+		_, err := col.InsertMany(ctx, docs[start:end], opts)
+		return err
+	})
+}
+
+// bulkWrite method applies models unordered, a batch at a time
+func (db *MDB) bulkWrite(ctx context.Context, col *mongo.Collection, models []mongo.WriteModel) error {
+
+	opts := options.BulkWrite().SetOrdered(false)
+
+	return writeBatches(len(models), db.batchSize(), func(start, end int) error {
+		_, err := col.BulkWrite(ctx, models[start:end], opts)
+		return err
+	})
+}
+
+// writeBatches function calls write for each batch of up to size of the n documents
+// Failed documents do not stop the writes, the write errors of every batch are returned together
+// as one mongo.BulkWriteException indexed into the n documents. Any other error stops at that batch.
+func writeBatches(n, size int, write func(start, end int) error) error {
+
+	var failed mongo.BulkWriteException
+	for start := 0; start < n; start += size {
+		end := start + size
+		if end > n {
+			end = n
+		}
+
+		err := write(start, end)
+		var bwe mongo.BulkWriteException
+		if !errors.As(err, &bwe) {
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		for _, we := range bwe.WriteErrors {
+			we.Index += start
+			failed.WriteErrors = append(failed.WriteErrors, we)
+		}
+		if failed.WriteConcernError == nil {
+			failed.WriteConcernError = bwe.WriteConcernError
+		}
+		failed.Labels = append(failed.Labels, bwe.Labels...)
+	}
+
+	if len(failed.WriteErrors) > 0 || failed.WriteConcernError != nil {
+		return failed
+	}
+	return nil
+}
+
+// writeErr method logs each document a bulk write failed on and classifies the error
+func (db *MDB) writeErr(op string, err error) error {
+
+	var bwe mongo.BulkWriteException
+	if errors.As(err, &bwe) {
+		for _, we := range bwe.WriteErrors {
+			db.logger().WithField("index", we.Index).Errorf("Error writing document in %s: %s", op, we.Message)
+		}
+	}
+
+	return wrapErr(op, err)
+}
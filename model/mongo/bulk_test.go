@@ -0,0 +1,57 @@
+package mongo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// TestWriteBatches function
+func TestWriteBatches(t *testing.T) {
+
+	var batches [][2]int
+	err := writeBatches(7, 3, func(start, end int) error {
+		batches = append(batches, [2]int{start, end})
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, [][2]int{{0, 3}, {3, 6}, {6, 7}}, batches)
+
+	// failed documents are collected across batches, indexed into all documents
+	calls := 0
+	err = writeBatches(7, 3, func(start, end int) error {
+		calls++
+		if start == 6 {
+			return nil
+		}
+		return mongo.BulkWriteException{WriteErrors: []mongo.BulkWriteError{
+			{WriteError: mongo.WriteError{Index: 1, Code: duplicateKeyCode, Message: "duplicate key"}},
+		}}
+	})
+	assert.Equal(t, 3, calls)
+	var bwe mongo.BulkWriteException
+	assert.True(t, errors.As(err, &bwe))
+	if assert.Len(t, bwe.WriteErrors, 2) {
+		assert.Equal(t, 1, bwe.WriteErrors[0].Index)
+		assert.Equal(t, 4, bwe.WriteErrors[1].Index)
+	}
+	assert.True(t, errors.Is(wrapErr("persistFuelSales", err), ErrConflict))
+
+	// other errors stop the writes
+	calls = 0
+	err = writeBatches(7, 3, func(start, end int) error {
+		calls++
+		return errors.New("connection refused")
+	})
+	assert.EqualError(t, err, "connection refused")
+	assert.Equal(t, 1, calls)
+}
+
+// TestBatchSize function
+func TestBatchSize(t *testing.T) {
+
+	assert.Equal(t, defaultBatchSize, (&MDB{}).batchSize())
+	assert.Equal(t, 50, (&MDB{BatchSize: 50}).batchSize())
+}
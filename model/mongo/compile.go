@@ -0,0 +1,551 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pulpfree/gsales-fs-export/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// cleanupTimeout limits removing staged docs once a compile has ended
+const cleanupTimeout = 5 * time.Second
+
+// mergeFuelSales method compiles the request's node sales into fuel-sales-export with one aggregation
+// The node sales are shaped as import docs, matched to their stations with a $lookup against station-nodes,
+// consolidated as compileStation does, given their margins and merged on _id, so nothing is staged in
+// fuel-sales-import or pulled back to upsert. $merge requires MongoDB 4.2 or later.
+func (db *MDB) mergeFuelSales(ctx context.Context, req *model.Request, ts int64) (err error) {
+
+	op := db.begin("mergeFuelSales")
+	defer func() { op.end(err) }()
+
+	col := db.db.Collection(colSales)
+
+	cur, err := col.Aggregate(ctx, mergePipeline(req, ts))
+	if err != nil {
+		return wrapErr("mergeFuelSales", err)
+	}
+	defer cur.Close(ctx)
+
+	// $merge returns no documents, draining the cursor surfaces any error the server reports after the first batch
+	for cur.Next(ctx) {
+	}
+	if err = cur.Err(); err != nil {
+		return wrapErr("mergeFuelSales", err)
+	}
+
+	return nil
+}
+
+// checkNodesMapped method returns ErrNodeNotMapped when any of the sales nodes is not in station-nodes
+func (db *MDB) checkNodesMapped(ctx context.Context, sales []model.StationSales) error {
+
+	stations, err := db.fetchStationNodes(ctx)
+	if err != nil {
+		return err
+	}
+
+	if nodes := unmappedNodes(sales, stations); len(nodes) > 0 {
+		db.logger().Errorf("Sales nodes without a station: %s", strings.Join(nodes, ", "))
+		return ErrNodeNotMapped.Wrap("checkNodesMapped", fmt.Errorf("nodes %s", strings.Join(nodes, ", ")))
+	}
+
+	return nil
+}
+
+// unmappedNodes function returns the sorted, distinct hex IDs of the sales nodes none of the stations include
+func unmappedNodes(sales []model.StationSales, stations []model.StationNodes) (nodes []string) {
+
+	mapped := make(map[primitive.ObjectID]bool)
+	for _, st := range stations {
+		for _, node := range st.Nodes {
+			mapped[node] = true
+		}
+	}
+
+	seen := make(map[primitive.ObjectID]bool)
+	for _, s := range sales {
+		if !mapped[s.StationID] && !seen[s.StationID] {
+			seen[s.StationID] = true
+			nodes = append(nodes, s.StationID.Hex())
+		}
+	}
+	sort.Strings(nodes)
+
+	return nodes
+}
+
+// stageFuelSales method compiles through the fuel-sales-import collection, one station at a time
+// It is the path for servers without $merge
+func (db *MDB) stageFuelSales(ctx context.Context, docs []model.StationSales, ts int64) error {
+
+	return staged(ctx,
+		func(ctx context.Context) error {
+			return db.persistFuelSales(ctx, docs, ts)
+		},
+		db.compileFuelSales,
+		func(ctx context.Context) error {
+			_, err := db.removeImportedFuelSales(ctx)
+			return err
+		},
+	)
+}
+
+// staged function runs persist then compile, and remove however they end
+// Docs left staged would be merged into the next compile, so remove runs with its own
+// cleanupTimeout context as ctx may be cancelled or past its deadline
+func staged(ctx context.Context, persist, compile, remove func(context.Context) error) (err error) {
+
+	defer func() {
+		cctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+		defer cancel()
+		if rErr := remove(cctx); rErr != nil && err == nil {
+			err = rErr
+		}
+	}()
+
+	if err = persist(ctx); err != nil {
+		return err
+	}
+
+	return compile(ctx)
+}
+
+// mergePipeline function returns the stages compiling the request's node sales into fuel-sales-export
+func mergePipeline(req *model.Request, ts int64) mongo.Pipeline {
+
+	return append(salesStages(req),
+		importProjection(ts),
+		bson.D{
+			primitive.E{
+				Key: "$lookup",
+				Value: bson.D{
+					primitive.E{
+						Key:   "from",
+						Value: colStationNodes,
+					},
+					primitive.E{
+						Key:   "localField",
+						Value: "stationID",
+					},
+					primitive.E{
+						Key:   "foreignField",
+						Value: "nodes",
+					},
+					primitive.E{
+						Key:   "as",
+						Value: "station",
+					},
+				},
+			},
+		},
+		// unmapped nodes are refused by checkNodesMapped, they are kept here rather than
+		// dropped so a node unmapped since then is not quietly left out of the totals
+		bson.D{
+			primitive.E{
+				Key: "$unwind",
+				Value: bson.D{
+					primitive.E{
+						Key:   "path",
+						Value: "$station",
+					},
+					primitive.E{
+						Key:   "preserveNullAndEmptyArrays",
+						Value: true,
+					},
+				},
+			},
+		},
+		compileGroup(bson.D{
+			primitive.E{
+				Key:   "recordDate",
+				Value: "$recordDate",
+			},
+			primitive.E{
+				Key:   "importTS",
+				Value: "$importTS",
+			},
+			primitive.E{
+				Key:   "stationID",
+				Value: "$station._id",
+			},
+		}),
+		compileProjection("$_id.stationID", bson.D{
+			primitive.E{
+				Key: "$concat",
+				Value: []interface{}{
+					bson.D{primitive.E{Key: "$toString", Value: "$_id.recordDate"}},
+					"-",
+					bson.D{primitive.E{Key: "$toString", Value: "$_id.stationID"}},
+				},
+			},
+		}),
+		marginsStage(),
+		bson.D{
+			primitive.E{
+				Key: "$merge",
+				Value: bson.D{
+					primitive.E{
+						Key:   "into",
+						Value: colFSExport,
+					},
+					primitive.E{
+						Key:   "on",
+						Value: "_id",
+					},
+					primitive.E{
+						Key:   "whenMatched",
+						Value: "merge",
+					},
+					primitive.E{
+						Key:   "whenNotMatched",
+						Value: "insert",
+					},
+				},
+			},
+		},
+	)
+}
+
+// importProjection function returns the $project shaping grouped node sales as persistFuelSales does,
+// splitting fuel_2 between NL and SNL
+func importProjection(ts int64) bson.D {
+
+	return bson.D{
+		primitive.E{
+			Key: "$project",
+			Value: bson.D{
+				primitive.E{
+					Key:   "_id",
+					Value: 0,
+				},
+				primitive.E{
+					Key:   "fuelCosts",
+					Value: "$fuelCosts",
+				},
+				primitive.E{
+					Key:   "fuelRevenue",
+					Value: splitFuels("$fuelDollars."),
+				},
+				primitive.E{
+					Key:   "fuelSales",
+					Value: splitFuels("$"),
+				},
+				primitive.E{
+					Key: "fuelSums",
+					Value: bson.D{
+						primitive.E{Key: "fuel1", Value: "$fuel1"},
+						primitive.E{Key: "fuel2", Value: "$fuel2"},
+						primitive.E{Key: "fuel3", Value: "$fuel3"},
+						primitive.E{Key: "fuel4", Value: "$fuel4"},
+						primitive.E{Key: "fuel5", Value: "$fuel5"},
+						primitive.E{Key: "fuel6", Value: "$fuel6"},
+					},
+				},
+				primitive.E{
+					Key: "importTS",
+					Value: bson.D{
+						primitive.E{
+							Key:   "$literal",
+							Value: ts,
+						},
+					},
+				},
+				primitive.E{
+					Key: "recordDate",
+					Value: bson.D{
+						primitive.E{
+							Key: "$toInt",
+							Value: bson.D{
+								primitive.E{
+									Key: "$dateToString",
+									Value: bson.D{
+										primitive.E{
+											Key:   "format",
+											Value: "%Y%m%d",
+										},
+										primitive.E{
+											Key:   "date",
+											Value: "$recordDate",
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+				primitive.E{
+					Key:   "stationID",
+					Value: "$stationID",
+				},
+			},
+		},
+	}
+}
+
+// splitFuels function returns the grade document for the fuel1 to fuel6 fields under prefix
+func splitFuels(prefix string) bson.D {
+
+	half := bson.D{
+		primitive.E{
+			Key:   "$divide",
+			Value: []interface{}{prefix + "fuel2", 2},
+		},
+	}
+
+	return bson.D{
+		primitive.E{
+			Key: model.GradeNL,
+			Value: bson.D{
+				primitive.E{
+					Key:   "$add",
+					Value: []interface{}{prefix + "fuel1", half},
+				},
+			},
+		},
+		primitive.E{
+			Key: model.GradeSNL,
+			Value: bson.D{
+				primitive.E{
+					Key:   "$add",
+					Value: []interface{}{prefix + "fuel3", half},
+				},
+			},
+		},
+		primitive.E{
+			Key:   model.GradeDSL,
+			Value: prefix + "fuel4",
+		},
+		primitive.E{
+			Key:   model.GradeCDSL,
+			Value: prefix + "fuel5",
+		},
+		primitive.E{
+			Key:   model.GradePROP,
+			Value: prefix + "fuel6",
+		},
+	}
+}
+
+// marginsStage function returns the $addFields computing fuelMargins as model.ComputeMargins does,
+// for every grade with litres sold
+func marginsStage() bson.D {
+
+	margins := []interface{}{}
+	for _, grade := range model.FuelGrades {
+		litres := "$fuelSales." + grade
+		cost := "$avgFuelCosts." + grade
+		revenue := "$fuelRevenue." + grade
+		cogs := bson.D{
+			primitive.E{
+				Key:   "$multiply",
+				Value: []interface{}{litres, cost},
+			},
+		}
+
+		margins = append(margins, bson.D{
+			primitive.E{
+				Key:   "k",
+				Value: grade,
+			},
+			primitive.E{
+				Key: "v",
+				Value: bson.D{
+					primitive.E{
+						Key:   "cogs",
+						Value: cogs,
+					},
+					primitive.E{
+						Key:   "cost",
+						Value: cost,
+					},
+					primitive.E{
+						Key:   "litres",
+						Value: litres,
+					},
+					primitive.E{
+						Key: "margin",
+						Value: bson.D{
+							primitive.E{
+								Key:   "$subtract",
+								Value: []interface{}{revenue, cogs},
+							},
+						},
+					},
+					primitive.E{
+						Key:   "revenue",
+						Value: revenue,
+					},
+				},
+			},
+		})
+	}
+
+	return bson.D{
+		primitive.E{
+			Key: "$addFields",
+			Value: bson.D{
+				primitive.E{
+					Key: "fuelMargins",
+					Value: bson.D{
+						primitive.E{
+							Key: "$arrayToObject",
+							Value: bson.D{
+								primitive.E{
+									Key: "$filter",
+									Value: bson.D{
+										primitive.E{
+											Key:   "input",
+											Value: margins,
+										},
+										primitive.E{
+											Key:   "as",
+											Value: "margin",
+										},
+										primitive.E{
+											Key: "cond",
+											Value: bson.D{
+												primitive.E{
+													Key:   "$ne",
+													Value: []interface{}{"$$margin.v.litres", 0},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// compileGroup function returns the $group consolidating node litres, costs and revenue under groupID
+func compileGroup(groupID bson.D) bson.D {
+
+	return bson.D{
+		primitive.E{
+			Key: "$group",
+			Value: append(bson.D{
+				primitive.E{
+					Key:   "_id",
+					Value: groupID,
+				},
+				primitive.E{
+					Key: "NL",
+					Value: bson.D{
+						primitive.E{
+							Key:   "$sum",
+							Value: "$fuelSales.NL",
+						},
+					},
+				},
+				primitive.E{
+					Key: "SNL",
+					Value: bson.D{
+						primitive.E{
+							Key:   "$sum",
+							Value: "$fuelSales.SNL",
+						},
+					},
+				},
+				primitive.E{
+					Key: "DSL",
+					Value: bson.D{
+						primitive.E{
+							Key:   "$sum",
+							Value: "$fuelSales.DSL",
+						},
+					},
+				},
+				primitive.E{
+					Key: "CDSL",
+					Value: bson.D{
+						primitive.E{
+							Key:   "$sum",
+							Value: "$fuelSales.CDSL",
+						},
+					},
+				},
+				primitive.E{
+					Key: "PROP",
+					Value: bson.D{
+						primitive.E{
+							Key:   "$sum",
+							Value: "$fuelSales.PROP",
+						},
+					},
+				},
+			}, append(gradeCostAverages(), gradeRevenueSums()...)...),
+		},
+	}
+}
+
+// compileProjection function returns the $project shaping a consolidated group as a fuel sales export doc
+// with the given stationID and _id, either of which may be an expression
+func compileProjection(stationID, id interface{}) bson.D {
+
+	return bson.D{
+		primitive.E{
+			Key: "$project",
+			Value: bson.D{
+				primitive.E{
+					Key:   "recordDate",
+					Value: "$_id.recordDate",
+				},
+				primitive.E{
+					Key:   "stationID",
+					Value: stationID,
+				},
+				primitive.E{
+					Key:   "avgFuelCost",
+					Value: weightedCost(model.GradeNL),
+				},
+				gradeCostProjection(),
+				unweightedCostProjection(),
+				gradeRevenueProjection(),
+				primitive.E{
+					Key:   "importTS",
+					Value: "$_id.importTS",
+				},
+				primitive.E{
+					Key:   "_id",
+					Value: id,
+				},
+				primitive.E{
+					Key: "fuelSales",
+					Value: bson.D{
+						primitive.E{
+							Key:   "NL",
+							Value: "$NL",
+						},
+						primitive.E{
+							Key:   "SNL",
+							Value: "$SNL",
+						},
+						primitive.E{
+							Key:   "DSL",
+							Value: "$DSL",
+						},
+						primitive.E{
+							Key:   "CDSL",
+							Value: "$CDSL",
+						},
+						primitive.E{
+							Key:   "PROP",
+							Value: "$PROP",
+						},
+					},
+				},
+			},
+		},
+	}
+}
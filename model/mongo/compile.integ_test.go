@@ -0,0 +1,186 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pulpfree/gsales-fs-export/config"
+	"github.com/pulpfree/gsales-fs-export/model"
+	"github.com/pulpfree/gsales-fs-export/validators"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// seed sizes for the compile benchmarks, roughly a month of the station network
+const (
+	seedDays     = 30
+	seedNodes    = 3
+	seedShifts   = 2
+	seedStations = 20
+)
+
+// seedCompileDB function connects to a scratch database and seeds station-nodes and sales
+// stations * nodes * days * shifts sales docs are inserted, covering the returned request dates
+func seedCompileDB(tb testing.TB, ctx context.Context, stations, nodes, days int) (*MDB, *model.Request) {
+
+	tb.Setenv("Stage", "test")
+	cfg := &config.Config{DefaultsFilePath: defaultsFP}
+	if err := cfg.Load(); err != nil {
+		tb.Skipf("Error in loading config: %s", err)
+	}
+
+	db, err := NewDB(ctx, cfg.GetMongoConnectURL(), cfg.MongoDBName+"-compile-bench")
+	if err != nil {
+		tb.Skipf("Error connecting to db: %s", err)
+	}
+	tb.Cleanup(func() {
+		db.db.Drop(context.Background())
+		db.Close()
+	})
+	require.NoError(tb, db.db.Drop(ctx))
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	var stationDocs, salesDocs []interface{}
+	for s := 0; s < stations; s++ {
+		station := model.StationNodes{ID: primitive.NewObjectID(), Name: fmt.Sprintf("Station %d", s)}
+		for n := 0; n < nodes; n++ {
+			node := primitive.NewObjectID()
+			station.Nodes = append(station.Nodes, node)
+			for d := 0; d < days; d++ {
+				for sh := 0; sh < seedShifts; sh++ {
+					salesDocs = append(salesDocs, seedSale(node, start.AddDate(0, 0, d), float64(s+n+d+sh+1)))
+				}
+			}
+		}
+		stationDocs = append(stationDocs, station)
+	}
+	_, err = db.db.Collection(colStationNodes).InsertMany(ctx, stationDocs)
+	require.NoError(tb, err)
+	_, err = db.db.Collection(colSales).InsertMany(ctx, salesDocs)
+	require.NoError(tb, err)
+
+	req, err := validators.RequestVars(&model.RequestInput{
+		DateStart:  start.Format(timeForm),
+		DateEnd:    start.AddDate(0, 0, days-1).Format(timeForm),
+		ExportType: "fuel",
+	})
+	require.NoError(tb, err)
+
+	return db, req
+}
+
+// seedSale function returns a shift's sales doc for a node, with litres and dollars scaled by n
+func seedSale(node primitive.ObjectID, recordDate time.Time, n float64) bson.D {
+
+	fuel := bson.D{}
+	for i := 1; i <= 6; i++ {
+		litres := n * 100 * float64(i)
+		fuel = append(fuel, primitive.E{
+			Key: fmt.Sprintf("fuel_%d", i),
+			Value: bson.D{
+				primitive.E{Key: "dollar", Value: litres * 1.45},
+				primitive.E{Key: "litre", Value: litres},
+			},
+		})
+	}
+
+	return bson.D{
+		primitive.E{Key: "fuelCosts", Value: &model.FuelCosts{Fuel1: 1.02 + n/100, Fuel3: 1.12, Fuel4: 1.21, Fuel5: 1.25, Fuel6: 0.61}},
+		primitive.E{Key: "recordDate", Value: recordDate},
+		primitive.E{Key: "salesSummary", Value: bson.D{primitive.E{Key: "fuel", Value: fuel}}},
+		primitive.E{Key: "stationID", Value: node},
+	}
+}
+
+// fetchExports function returns the compiled export docs ordered by _id
+func fetchExports(tb testing.TB, ctx context.Context, db *MDB) (docs []model.FuelSalesExport) {
+
+	cur, err := db.db.Collection(colFSExport).Find(ctx, bson.D{}, options.Find().SetSort(bson.D{primitive.E{Key: "_id", Value: 1}}))
+	require.NoError(tb, err)
+	require.NoError(tb, cur.All(ctx, &docs))
+
+	return docs
+}
+
+// TestMergeMatchesStaged function checks the $merge compile writes the same export docs as staging
+func TestMergeMatchesStaged(t *testing.T) {
+
+	ctx := context.Background()
+	db, req := seedCompileDB(t, ctx, 3, seedNodes, 5)
+	ts := time.Now().Unix()
+
+	sales, err := db.fetchFuelSales(ctx, req)
+	require.NoError(t, err)
+	require.NoError(t, db.stageFuelSales(ctx, sales, ts))
+	staged := fetchExports(t, ctx, db)
+
+	require.NoError(t, db.db.Collection(colFSExport).Drop(ctx))
+	require.NoError(t, db.mergeFuelSales(ctx, req, ts))
+	merged := fetchExports(t, ctx, db)
+
+	assert.Len(t, staged, 3*5)
+	assert.Equal(t, staged, merged)
+}
+
+// TestCreateFuelSalesUnmappedNode function checks a node missing from station-nodes fails the
+// compile before anything is written, rather than its sales being left out
+func TestCreateFuelSalesUnmappedNode(t *testing.T) {
+
+	ctx := context.Background()
+	db, req := seedCompileDB(t, ctx, 2, seedNodes, 3)
+
+	_, err := db.db.Collection(colSales).InsertOne(ctx, seedSale(primitive.NewObjectID(), req.DateStart, 1))
+	require.NoError(t, err)
+
+	_, err = db.CreateFuelSales(ctx, req)
+	assert.True(t, errors.Is(err, ErrNodeNotMapped))
+	assert.Empty(t, fetchExports(t, ctx, db))
+
+	n, err := db.db.Collection(colImportLog).CountDocuments(ctx, bson.D{})
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+// BenchmarkCompileStaged function compiles through fuel-sales-import one station at a time
+func BenchmarkCompileStaged(b *testing.B) {
+
+	ctx := context.Background()
+	db, req := seedCompileDB(b, ctx, seedStations, seedNodes, seedDays)
+	ts := time.Now().Unix()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sales, err := db.fetchFuelSales(ctx, req)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err = db.stageFuelSales(ctx, sales, ts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkCompileMerge function compiles with the single $merge aggregation
+// The source sales are still fetched, as CreateFuelSales returns them
+func BenchmarkCompileMerge(b *testing.B) {
+
+	ctx := context.Background()
+	db, req := seedCompileDB(b, ctx, seedStations, seedNodes, seedDays)
+	ts := time.Now().Unix()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := db.fetchFuelSales(ctx, req); err != nil {
+			b.Fatal(err)
+		}
+		if err := db.mergeFuelSales(ctx, req, ts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
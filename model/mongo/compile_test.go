@@ -0,0 +1,127 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pulpfree/gsales-fs-export/model"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestMergePipeline function
+func TestMergePipeline(t *testing.T) {
+
+	req := &model.Request{DateStart: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), DateEnd: time.Date(2020, 1, 31, 0, 0, 0, 0, time.UTC)}
+	pipeline := mergePipeline(req, 1577836800)
+
+	var stages []string
+	for _, stage := range pipeline {
+		stages = append(stages, stage[0].Key)
+	}
+	assert.Equal(t, []string{"$match", "$group", "$project", "$project", "$lookup", "$unwind", "$group", "$project", "$addFields", "$merge"}, stages)
+
+	// a bare number in $project would include the field rather than set it
+	importTS := pipeline[3][0].Value.(bson.D).Map()["importTS"]
+	assert.Equal(t, bson.D{primitive.E{Key: "$literal", Value: int64(1577836800)}}, importTS)
+
+	// node sales without a station are kept rather than dropped
+	unwind := pipeline[5][0].Value.(bson.D).Map()
+	assert.Equal(t, "$station", unwind["path"])
+	assert.Equal(t, true, unwind["preserveNullAndEmptyArrays"])
+
+	merge := pipeline[len(pipeline)-1][0].Value.(bson.D).Map()
+	assert.Equal(t, colFSExport, merge["into"])
+	assert.Equal(t, "merge", merge["whenMatched"])
+}
+
+// TestUnmappedNodes function
+func TestUnmappedNodes(t *testing.T) {
+
+	mapped, other := primitive.NewObjectID(), primitive.NewObjectID()
+	unmapped := primitive.NewObjectID()
+	stations := []model.StationNodes{{ID: primitive.NewObjectID(), Nodes: []primitive.ObjectID{mapped, other}}}
+
+	sales := []model.StationSales{{StationID: mapped}, {StationID: unmapped}, {StationID: other}, {StationID: unmapped}}
+	assert.Equal(t, []string{unmapped.Hex()}, unmappedNodes(sales, stations))
+
+	assert.Empty(t, unmappedNodes(sales[:1], stations))
+}
+
+// TestStagedCleanup function checks staged docs are removed when the compile is cancelled part way
+func TestStagedCleanup(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var removed bool
+	err := staged(ctx,
+		func(ctx context.Context) error { return nil },
+		func(ctx context.Context) error {
+			cancel()
+			return wrapErr("compileFuelSales", ctx.Err())
+		},
+		func(ctx context.Context) error {
+			removed = true
+			assert.NoError(t, ctx.Err(), "remove should not inherit the cancelled context")
+			_, ok := ctx.Deadline()
+			assert.True(t, ok)
+			return nil
+		},
+	)
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.True(t, removed)
+
+	// a failed persist may have staged some docs, and a failed remove is reported
+	removeErr := errors.New("remove failed")
+	err = staged(context.Background(),
+		func(ctx context.Context) error { return nil },
+		func(ctx context.Context) error { return nil },
+		func(ctx context.Context) error { return removeErr },
+	)
+	assert.Equal(t, removeErr, err)
+
+	removed = false
+	persistErr := errors.New("persist failed")
+	err = staged(context.Background(),
+		func(ctx context.Context) error { return persistErr },
+		func(ctx context.Context) error { t.Error("compile should not run"); return nil },
+		func(ctx context.Context) error { removed = true; return nil },
+	)
+	assert.Equal(t, persistErr, err)
+	assert.True(t, removed)
+}
+
+// TestGradeCostAverages function checks each grade cost is weighted by the litres of the fuel it was paid for
+// NL and SNL sales include half the fuel_2 blend, so weighting by them would skew the fuel_1 and fuel_3 costs
+func TestGradeCostAverages(t *testing.T) {
+
+	fields := gradeCostAverages().Map()
+	for grade, fuel := range map[string]string{model.GradeNL: "fuel1", model.GradeSNL: "fuel3", model.GradeDSL: "fuel4"} {
+		costField := "$fuelCosts." + gradeCostFields[grade]
+		hasCost := bson.D{primitive.E{Key: "$gt", Value: []interface{}{costField, 0}}}
+
+		costLitres := bson.D{primitive.E{Key: "$sum", Value: bson.D{primitive.E{Key: "$cond", Value: []interface{}{
+			hasCost,
+			bson.D{primitive.E{Key: "$multiply", Value: []interface{}{costField, "$fuelSums." + fuel}}},
+			0,
+		}}}}}
+		assert.Equal(t, costLitres, fields["costLitres"+grade], grade)
+
+		costVolume := bson.D{primitive.E{Key: "$sum", Value: bson.D{primitive.E{Key: "$cond", Value: []interface{}{
+			hasCost,
+			"$fuelSums." + fuel,
+			0,
+		}}}}}
+		assert.Equal(t, costVolume, fields["costVolume"+grade], grade)
+	}
+
+	// the staged imports carry the litres the weights are read from
+	project := importProjection(1577836800)[0].Value.(bson.D).Map()
+	sums := project["fuelSums"].(bson.D).Map()
+	assert.Equal(t, "$fuel1", sums["fuel1"])
+	assert.Equal(t, "$fuel3", sums["fuel3"])
+}
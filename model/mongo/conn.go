@@ -0,0 +1,142 @@
+package mongo
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pulpfree/gsales-fs-export/model"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// ErrInvalidURI is returned when the connection string cannot be parsed
+var ErrInvalidURI = &model.Error{Code: "mongo_invalid_uri", Kind: model.KindInternal, Msg: "Invalid Mongo connection string"}
+
+const connectTimeout = 10 * time.Second
+
+// client interface is the part of *mongo.Client used here, so tests can stand in for a server
+type client interface {
+	Database(name string, opts ...*options.DatabaseOptions) *mongo.Database
+	Disconnect(ctx context.Context) error
+	Ping(ctx context.Context, rp *readpref.ReadPref) error
+}
+
+// connectFunc creates a client for the connection string
+type connectFunc func(ctx context.Context, uri string) (client, error)
+
+// Manager struct lazily creates a single client and shares it between calls to DB
+// In Lambda the manager lives for the container, so warm invocations reuse the connection.
+// The client is pinged before each use and replaced when the ping fails.
+type Manager struct {
+	connect connectFunc
+	client  client
+	dbName  string
+	mu      sync.Mutex
+	uri     string
+}
+
+var (
+	managers   = make(map[string]*Manager)
+	managersMu sync.Mutex
+)
+
+// NewManager function returns a manager for the connection string and database
+func NewManager(uri, dbName string) *Manager {
+	return &Manager{connect: connectClient, dbName: dbName, uri: uri}
+}
+
+// SharedManager function returns the process wide manager for the connection string and database,
+// creating it on first use
+func SharedManager(uri, dbName string) *Manager {
+	managersMu.Lock()
+	defer managersMu.Unlock()
+
+	key := uri + "|" + dbName
+	m, ok := managers[key]
+	if !ok {
+		m = NewManager(uri, dbName)
+		managers[key] = m
+	}
+	return m
+}
+
+// DB method returns an MDB using the shared client, connecting or reconnecting as needed
+// Closing the returned MDB leaves the shared client connected
+func (m *Manager) DB(ctx context.Context) (*MDB, error) {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, connectTimeout)
+	defer cancel()
+
+	if m.client != nil {
+		err := m.client.Ping(ctx, nil)
+		if err == nil {
+			return m.mdb(), nil
+		}
+		log.Warnf("Mongo ping failed, reconnecting: %s", err)
+		m.drop(ctx)
+	}
+
+	c, err := m.connect(ctx, m.uri)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.Ping(ctx, nil); err != nil {
+		c.Disconnect(ctx)
+		return nil, wrapErr("Ping", err)
+	}
+	m.client = c
+	log.Info("Connected to MongoDB")
+
+	return m.mdb(), nil
+}
+
+// Close method disconnects the shared client, the next call to DB reconnects
+func (m *Manager) Close() error {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.client == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	defer cancel()
+
+	err := m.client.Disconnect(ctx)
+	m.client = nil
+	return wrapErr("Disconnect", err)
+}
+
+func (m *Manager) mdb() *MDB {
+	return &MDB{client: m.client, dbName: m.dbName, db: m.client.Database(m.dbName), shared: true}
+}
+
+// drop method disconnects a failed client, ignoring errors as the connection is already unusable
+func (m *Manager) drop(ctx context.Context) {
+	if err := m.client.Disconnect(ctx); err != nil {
+		log.Debugf("Error disconnecting failed mongo client: %s", err)
+	}
+	m.client = nil
+}
+
+// connectClient function creates a driver client, the connection itself is made on first use
+func connectClient(ctx context.Context, uri string) (client, error) {
+
+	opts := options.Client().ApplyURI(uri)
+	if err := opts.Validate(); err != nil {
+		return nil, ErrInvalidURI.Wrap("Connect", err)
+	}
+
+	c, err := mongo.Connect(ctx, opts)
+	if err != nil {
+		return nil, wrapErr("Connect", err)
+	}
+	return c, nil
+}
@@ -0,0 +1,145 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// fakeClient returns each queued ping error in turn, then nil
+type fakeClient struct {
+	disconnected bool
+	pingErrs     []error
+	pings        int
+}
+
+func (c *fakeClient) Database(name string, opts ...*options.DatabaseOptions) *mongo.Database {
+	return nil
+}
+
+func (c *fakeClient) Disconnect(ctx context.Context) error {
+	c.disconnected = true
+	return nil
+}
+
+func (c *fakeClient) Ping(ctx context.Context, rp *readpref.ReadPref) error {
+	c.pings++
+	if len(c.pingErrs) == 0 {
+		return nil
+	}
+	err := c.pingErrs[0]
+	c.pingErrs = c.pingErrs[1:]
+	return err
+}
+
+// fakeConnect returns the clients in order, or err once they run out
+func fakeConnect(err error, clients ...*fakeClient) (connectFunc, *int) {
+	calls := 0
+	return func(ctx context.Context, uri string) (client, error) {
+		calls++
+		if len(clients) == 0 {
+			return nil, err
+		}
+		c := clients[0]
+		clients = clients[1:]
+		return c, nil
+	}, &calls
+}
+
+// TestManagerReuse function
+func TestManagerReuse(t *testing.T) {
+
+	c := &fakeClient{}
+	m := NewManager("mongodb://localhost", "sales")
+	var calls *int
+	m.connect, calls = fakeConnect(nil, c)
+
+	db1, err := m.DB(context.Background())
+	assert.NoError(t, err)
+	db2, err := m.DB(context.Background())
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, *calls)
+	assert.Equal(t, 2, c.pings)
+	assert.Equal(t, "sales", db2.dbName)
+
+	// closing a shared MDB leaves the client connected
+	db1.Close()
+	assert.False(t, c.disconnected)
+
+	assert.NoError(t, m.Close())
+	assert.True(t, c.disconnected)
+	assert.NoError(t, m.Close())
+}
+
+// TestManagerReconnect function
+func TestManagerReconnect(t *testing.T) {
+
+	stale := &fakeClient{}
+	fresh := &fakeClient{}
+	m := NewManager("mongodb://localhost", "sales")
+	var calls *int
+	m.connect, calls = fakeConnect(nil, stale, fresh)
+
+	_, err := m.DB(context.Background())
+	assert.NoError(t, err)
+
+	stale.pingErrs = []error{errors.New("connection reset")}
+	_, err = m.DB(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, *calls)
+	assert.True(t, stale.disconnected)
+	assert.Equal(t, fresh, m.client)
+}
+
+// TestManagerConnectErrors function
+func TestManagerConnectErrors(t *testing.T) {
+
+	m := NewManager("mongodb://localhost", "sales")
+	var calls *int
+	m.connect, calls = fakeConnect(ErrUnavailable.Wrap("Connect", errors.New("refused")))
+
+	_, err := m.DB(context.Background())
+	assert.True(t, errors.Is(err, ErrUnavailable))
+	assert.Nil(t, m.client)
+
+	// a failed connection is retried on the next call
+	_, err = m.DB(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, 2, *calls)
+}
+
+// TestManagerPingError function
+func TestManagerPingError(t *testing.T) {
+
+	c := &fakeClient{pingErrs: []error{context.DeadlineExceeded}}
+	m := NewManager("mongodb://localhost", "sales")
+	m.connect, _ = fakeConnect(nil, c)
+
+	_, err := m.DB(context.Background())
+	assert.True(t, errors.Is(err, ErrTimeout))
+	assert.True(t, c.disconnected)
+	assert.Nil(t, m.client)
+}
+
+// TestManagerInvalidURI function
+func TestManagerInvalidURI(t *testing.T) {
+
+	_, err := NewManager("postgres://localhost", "sales").DB(context.Background())
+	assert.True(t, errors.Is(err, ErrInvalidURI))
+
+	_, err = NewDB(context.Background(), "postgres://localhost", "sales")
+	assert.True(t, errors.Is(err, ErrInvalidURI))
+}
+
+// TestSharedManager function
+func TestSharedManager(t *testing.T) {
+	a := SharedManager("mongodb://localhost", "sales")
+	assert.Same(t, a, SharedManager("mongodb://localhost", "sales"))
+	assert.NotSame(t, a, SharedManager("mongodb://localhost", "other"))
+}
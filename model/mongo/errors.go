@@ -0,0 +1,68 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/pulpfree/gsales-fs-export/model"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Mongo error sentinels
+// A node without a station is a gap in station-nodes, its sales would be left out of the export
+var (
+	ErrConflict      = &model.Error{Code: "mongo_conflict", Kind: model.KindConflict, Msg: "Mongo duplicate record"}
+	ErrNodeNotMapped = &model.Error{Code: "node_not_mapped", Kind: model.KindInternal, Msg: "Sales node is not mapped to a station"}
+	ErrTimeout       = &model.Error{Code: "mongo_timeout", Kind: model.KindTimeout, Msg: "Mongo operation timed out"}
+	ErrUnavailable   = &model.Error{Code: "mongo_unavailable", Kind: model.KindUpstream, Msg: "Mongo operation failed"}
+)
+
+// Mongo server error codes
+const (
+	duplicateKeyCode      = 11000
+	unrecognizedStageCode = 40324
+)
+
+// wrapErr classifies a driver error into one of the package sentinels
+func wrapErr(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var cmdErr mongo.CommandError
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &cmdErr) && cmdErr.IsMaxTimeMSExpiredError()) {
+		return ErrTimeout.Wrap(op, err)
+	}
+	if isDuplicateKey(err) {
+		return ErrConflict.Wrap(op, err)
+	}
+
+	return ErrUnavailable.Wrap(op, err)
+}
+
+func isDuplicateKey(err error) bool {
+	var we mongo.WriteException
+	if errors.As(err, &we) {
+		for _, e := range we.WriteErrors {
+			if e.Code == duplicateKeyCode {
+				return true
+			}
+		}
+	}
+	var bwe mongo.BulkWriteException
+	if errors.As(err, &bwe) {
+		for _, e := range bwe.WriteErrors {
+			if e.Code == duplicateKeyCode {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isUnsupportedStage function reports whether the server rejected an aggregation stage it does not know,
+// such as $merge before MongoDB 4.2
+func isUnsupportedStage(err error) bool {
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) && cmdErr.Code == unrecognizedStageCode
+}
@@ -0,0 +1,34 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// TestWrapErr function
+func TestWrapErr(t *testing.T) {
+
+	assert.NoError(t, wrapErr("op", nil))
+
+	err := wrapErr("fetchFuelSales", context.DeadlineExceeded)
+	assert.True(t, errors.Is(err, ErrTimeout))
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	dupErr := mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: duplicateKeyCode}}}
+	assert.True(t, errors.Is(wrapErr("createImportLog", dupErr), ErrConflict))
+
+	assert.True(t, errors.Is(wrapErr("fetchStationNodes", errors.New("connection refused")), ErrUnavailable))
+}
+
+// TestIsUnsupportedStage function
+func TestIsUnsupportedStage(t *testing.T) {
+
+	stageErr := mongo.CommandError{Code: unrecognizedStageCode, Message: "Unrecognized pipeline stage name: '$merge'"}
+	assert.True(t, isUnsupportedStage(wrapErr("mergeFuelSales", stageErr)))
+	assert.False(t, isUnsupportedStage(wrapErr("mergeFuelSales", mongo.CommandError{Code: 2})))
+	assert.False(t, isUnsupportedStage(nil))
+}
@@ -2,6 +2,7 @@ package mongo
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
 	"time"
@@ -9,7 +10,10 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/pulpfree/gsales-fs-export/config"
+	"github.com/pulpfree/gsales-fs-export/logging"
+	"github.com/pulpfree/gsales-fs-export/metrics"
 	"github.com/pulpfree/gsales-fs-export/model"
+	"github.com/pulpfree/gsales-fs-export/tracing"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -17,10 +21,19 @@ import (
 )
 
 // MDB struct
+// Metrics, when set, records the latency, failures and documents fetched per operation
+// Trace, when set, is the parent span of each operation
+// Log, when set, carries the request fields and correlation id
+// BatchSize is the number of documents per bulk write, defaultBatchSize when not set
 type MDB struct {
-	client *mongo.Client
-	dbName string
-	db     *mongo.Database
+	BatchSize int
+	Log       *log.Entry
+	Metrics   *metrics.Logger
+	Trace     *tracing.Span
+	client    client
+	dbName    string
+	db        *mongo.Database
+	shared    bool
 }
 
 // DB and collections Constants
@@ -34,6 +47,26 @@ const (
 	colStationNodes = "station-nodes"
 )
 
+// gradeCostFields maps each export grade to its field in fuelCosts
+// fuel_2 is a blend split between NL and SNL so has no grade of its own
+var gradeCostFields = map[string]string{
+	model.GradeNL:   "fuel_1",
+	model.GradeSNL:  "fuel_3",
+	model.GradeDSL:  "fuel_4",
+	model.GradeCDSL: "fuel_5",
+	model.GradePROP: "fuel_6",
+}
+
+// gradeCostLitres maps each export grade to the fuelSums litres its cost is weighted by
+// NL and SNL sales include half the fuel_2 blend, which is not bought at the fuel_1 or fuel_3 cost
+var gradeCostLitres = map[string]string{
+	model.GradeNL:   "fuel1",
+	model.GradeSNL:  "fuel3",
+	model.GradeDSL:  "fuel4",
+	model.GradeCDSL: "fuel5",
+	model.GradePROP: "fuel6",
+}
+
 // Time format constants
 const (
 	timeShortForm = "20060102"
@@ -42,95 +75,90 @@ const (
 // ==================== Exported methods ==================== //
 
 // NewDB connection function
-func NewDB(connection string, dbNm string) (*MDB, error) {
-
-	clientOptions := options.Client().ApplyURI(connection)
-	err := clientOptions.Validate()
-	if err != nil {
-		log.Fatal(err)
-	}
+// The MDB owns its client and Close disconnects it, use a Manager to share a client between requests
+func NewDB(ctx context.Context, connection string, dbNm string) (*MDB, error) {
 
-	// Connect to MongoDB
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, connectTimeout)
 	defer cancel()
-	client, err := mongo.Connect(ctx, clientOptions)
+
+	c, err := connectClient(ctx, connection)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	// Check the connection
-	err = client.Ping(ctx, nil)
-	if err != nil {
-		log.Fatal(err)
+	if err = c.Ping(ctx, nil); err != nil {
+		c.Disconnect(ctx)
+		return nil, wrapErr("Ping", err)
 	}
 
-	log.Println("Connected to MongoDB!")
+	log.Info("Connected to MongoDB")
 
 	return &MDB{
-		client: client,
+		client: c,
 		dbName: dbNm,
-		db:     client.Database(dbNm),
-	}, err
+		db:     c.Database(dbNm),
+	}, nil
 }
 
-// CreateFuelSales function
-func (db *MDB) CreateFuelSales(req *model.Request) (err error) {
+// CreateFuelSales function returns the source station sales the export was compiled from
+// The export is compiled on the server with $merge, or staged through fuel-sales-import when $merge is not supported
+func (db *MDB) CreateFuelSales(ctx context.Context, req *model.Request) (sales []model.StationSales, err error) {
 
-	sales, err := db.fetchFuelSales(req)
+	sales, err = db.fetchFuelSales(ctx, req)
 	if err != nil {
-		return err
+		return sales, err
 	}
 
-	ts, err := db.persistFuelSales(sales)
-	if err != nil {
-		return err
+	// both the merge and the staged compile only see the sales of mapped nodes
+	if err = db.checkNodesMapped(ctx, sales); err != nil {
+		return sales, err
 	}
 
-	_, err = db.createImportLog(req, ts)
-	if err != nil {
-		return err
+	ts := time.Now().Unix()
+	err = db.mergeFuelSales(ctx, req, ts)
+	if isUnsupportedStage(err) {
+		db.logger().Warnf("Mongo does not support $merge, staging fuel sales in %s: %s", colFSImport, err)
+		err = db.stageFuelSales(ctx, sales, ts)
 	}
-
-	err = db.compileFuelSales()
 	if err != nil {
-		return err
+		return sales, err
 	}
 
-	_, err = db.removeImportedFuelSales()
-	if err != nil {
-		return err
-	}
+	// the import is only logged once its export docs are written
+	_, err = db.createImportLog(ctx, req, ts)
 
-	return err
+	return sales, err
 }
 
-// CreatePropaneSales function
-func (db *MDB) CreatePropaneSales(req *model.Request) (err error) {
+// CreatePropaneSales function returns the source propane sales the export was created from
+func (db *MDB) CreatePropaneSales(ctx context.Context, req *model.Request) (sales []model.PropaneSale, err error) {
 
-	sales, err := db.fetchPropaneSales(req)
+	sales, err = db.fetchPropaneSales(ctx, req)
 	if err != nil {
-		return err
+		return sales, err
 	}
 
-	ts, err := db.persistPropaneSales(sales)
+	ts, err := db.persistPropaneSales(ctx, sales)
 	if err != nil {
-		return err
+		return sales, err
 	}
 
-	_, err = db.createImportLog(req, ts)
+	_, err = db.createImportLog(ctx, req, ts)
 	if err != nil {
-		return err
+		return sales, err
 	}
 
-	return err
+	return sales, err
 }
 
 // FetchExportedFuelSales method
-func (db *MDB) FetchExportedFuelSales(req *model.Request) (docs []*model.FuelSalesExport, err error) {
+func (db *MDB) FetchExportedFuelSales(ctx context.Context, req *model.Request) (docs []*model.FuelSalesExport, err error) {
+
+	op := db.begin("FetchExportedFuelSales")
+	defer func() { op.fetched(len(docs)).end(err) }()
 
 	// fetch previously exported records by date range
 	col := db.db.Collection(colFSExport)
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
-	defer cancel()
 
 	stDte, _ := strconv.Atoi(req.DateStart.Format(timeShortForm))
 	enDte, _ := strconv.Atoi(req.DateEnd.Format(timeShortForm))
@@ -150,25 +178,30 @@ func (db *MDB) FetchExportedFuelSales(req *model.Request) (docs []*model.FuelSal
 			},
 		},
 	}
-	cur, err := col.Find(ctx, filter)
+	opts := options.Find().SetSort(bson.D{
+		primitive.E{Key: "recordDate", Value: 1},
+		primitive.E{Key: "stationID", Value: 1},
+	})
+	cur, err := col.Find(ctx, filter, opts)
 	if err != nil {
-		return nil, err
+		return nil, wrapErr("FetchExportedFuelSales", err)
 	}
 	defer cur.Close(ctx)
 
 	if err := cur.All(ctx, &docs); err != nil {
-		return nil, err
+		return nil, wrapErr("FetchExportedFuelSales", err)
 	}
 	return docs, err
 }
 
 // FetchExportedPropaneSales method
-func (db *MDB) FetchExportedPropaneSales(req *model.Request) (docs []*model.PropaneSaleExport, err error) {
+func (db *MDB) FetchExportedPropaneSales(ctx context.Context, req *model.Request) (docs []*model.PropaneSaleExport, err error) {
+
+	op := db.begin("FetchExportedPropaneSales")
+	defer func() { op.fetched(len(docs)).end(err) }()
 
 	// fetch previously exported records by date range
 	col := db.db.Collection(colPSExport)
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
-	defer cancel()
 
 	stDte, _ := strconv.Atoi(req.DateStart.Format(timeShortForm))
 	enDte, _ := strconv.Atoi(req.DateEnd.Format(timeShortForm))
@@ -188,28 +221,66 @@ func (db *MDB) FetchExportedPropaneSales(req *model.Request) (docs []*model.Prop
 			},
 		},
 	}
-	cur, err := col.Find(ctx, filter)
+	opts := options.Find().SetSort(bson.D{
+		primitive.E{Key: "recordDate", Value: 1},
+		primitive.E{Key: "tankID", Value: 1},
+	})
+	cur, err := col.Find(ctx, filter, opts)
 	if err != nil {
-		return nil, err
+		return nil, wrapErr("FetchExportedPropaneSales", err)
 	}
 	defer cur.Close(ctx)
 
 	if err := cur.All(ctx, &docs); err != nil {
-		return nil, err
+		return nil, wrapErr("FetchExportedPropaneSales", err)
 	}
 
 	return docs, err
 }
 
+// FetchStationNodes method
+func (db *MDB) FetchStationNodes(ctx context.Context) (nodes []model.StationNodes, err error) {
+	return db.fetchStationNodes(ctx)
+}
+
 // ==================== FuelSales methods ==================== //
 
-func (db *MDB) fetchFuelSales(req *model.Request) (docs []model.StationSales, err error) {
+func (db *MDB) fetchFuelSales(ctx context.Context, req *model.Request) (docs []model.StationSales, err error) {
+
+	op := db.begin("fetchFuelSales")
+	defer func() { op.fetched(len(docs)).end(err) }()
 
 	col := db.db.Collection(colSales)
-	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second)
-	defer cancel()
 
-	pipeline := mongo.Pipeline{
+	pipeline := append(salesStages(req), bson.D{
+		primitive.E{
+			Key: "$sort",
+			Value: bson.D{
+				primitive.E{
+					Key:   "_id.recordDate",
+					Value: 1,
+				},
+			},
+		},
+	})
+
+	cur, err := col.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, wrapErr("fetchFuelSales", err)
+	}
+	defer cur.Close(ctx)
+
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, wrapErr("fetchFuelSales", err)
+	}
+
+	return docs, err
+}
+
+// salesStages function returns the stages summing node sales, dollars and the last fuel costs
+// per record date and station node for the request dates
+func salesStages(req *model.Request) mongo.Pipeline {
+	return mongo.Pipeline{
 		{
 			primitive.E{
 				Key: "$match",
@@ -233,7 +304,7 @@ func (db *MDB) fetchFuelSales(req *model.Request) (docs []model.StationSales, er
 		{
 			primitive.E{
 				Key: "$group",
-				Value: bson.D{
+				Value: append(bson.D{
 					primitive.E{
 						Key: "_id",
 						Value: bson.D{
@@ -310,7 +381,7 @@ func (db *MDB) fetchFuelSales(req *model.Request) (docs []model.StationSales, er
 							},
 						},
 					},
-				},
+				}, fuelDollarSums()...),
 			},
 		},
 		{
@@ -353,42 +424,68 @@ func (db *MDB) fetchFuelSales(req *model.Request) (docs []model.StationSales, er
 						Key:   "fuelCosts",
 						Value: 1,
 					},
-				},
-			},
-		},
-		{
-			primitive.E{
-				Key: "$sort",
-				Value: bson.D{
 					primitive.E{
-						Key:   "_id.recordDate",
-						Value: 1,
+						Key: "fuelDollars",
+						Value: bson.D{
+							primitive.E{
+								Key:   "fuel1",
+								Value: "$fuel1Dollar",
+							},
+							primitive.E{
+								Key:   "fuel2",
+								Value: "$fuel2Dollar",
+							},
+							primitive.E{
+								Key:   "fuel3",
+								Value: "$fuel3Dollar",
+							},
+							primitive.E{
+								Key:   "fuel4",
+								Value: "$fuel4Dollar",
+							},
+							primitive.E{
+								Key:   "fuel5",
+								Value: "$fuel5Dollar",
+							},
+							primitive.E{
+								Key:   "fuel6",
+								Value: "$fuel6Dollar",
+							},
+						},
 					},
 				},
 			},
 		},
 	}
+}
 
-	cur, err := col.Aggregate(ctx, pipeline)
-	if err != nil {
-		return nil, err
-	}
-	defer cur.Close(ctx)
+// fuelDollarSums returns the $group accumulators summing sales dollars per fuel
+func fuelDollarSums() (fields bson.D) {
 
-	if err := cur.All(ctx, &docs); err != nil {
-		return nil, err
+	for i := 1; i <= 6; i++ {
+		fields = append(fields, primitive.E{
+			Key: fmt.Sprintf("fuel%dDollar", i),
+			Value: bson.D{
+				primitive.E{
+					Key:   "$sum",
+					Value: fmt.Sprintf("$salesSummary.fuel.fuel_%d.dollar", i),
+				},
+			},
+		})
 	}
 
-	return docs, err
+	return fields
 }
 
-func (db *MDB) persistFuelSales(docs []model.StationSales) (ts int64, err error) {
+// persistFuelSales method stages the station node sales in fuel-sales-import under the import timestamp
+func (db *MDB) persistFuelSales(ctx context.Context, docs []model.StationSales, ts int64) (err error) {
+
+	op := db.begin("persistFuelSales")
+	defer func() { op.end(err) }()
 
 	col := db.db.Collection(colFSImport)
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
 
-	ts = time.Now().Unix()
+	imports := make([]interface{}, 0, len(docs))
 	for _, elem := range docs {
 		fuelSplit := elem.Fuel2 / 2
 		rdte, _ := strconv.Atoi(elem.RecordDate.Format(timeShortForm))
@@ -399,6 +496,17 @@ func (db *MDB) persistFuelSales(docs []model.StationSales) (ts int64, err error)
 			CDSL: elem.Fuel5,
 			PROP: elem.Fuel6,
 		}
+		var fr *model.FuelRevenue
+		if elem.FuelDollars != nil {
+			dollarSplit := elem.FuelDollars.Fuel2 / 2
+			fr = &model.FuelRevenue{
+				NL:   (elem.FuelDollars.Fuel1 + dollarSplit),
+				SNL:  (elem.FuelDollars.Fuel3 + dollarSplit),
+				DSL:  elem.FuelDollars.Fuel4,
+				CDSL: elem.FuelDollars.Fuel5,
+				PROP: elem.FuelDollars.Fuel6,
+			}
+		}
 		fsums := &model.FuelSums{
 			Fuel1: elem.Fuel1,
 			Fuel2: elem.Fuel2,
@@ -408,276 +516,339 @@ func (db *MDB) persistFuelSales(docs []model.StationSales) (ts int64, err error)
 			Fuel6: elem.Fuel6,
 		}
 		fsi := &model.FuelSalesImport{
-			FuelCosts:  elem.FuelCosts,
-			FuelSales:  fs,
-			FuelSums:   fsums,
-			ImportTS:   ts,
-			RecordDate: rdte,
-			StationID:  elem.StationID,
-			Status:     "imported",
+			FuelCosts:   elem.FuelCosts,
+			FuelRevenue: fr,
+			FuelSales:   fs,
+			FuelSums:    fsums,
+			ImportTS:    ts,
+			RecordDate:  rdte,
+			StationID:   elem.StationID,
+			Status:      "imported",
 		}
+		imports = append(imports, fsi)
+	}
 
-		if _, err := col.InsertOne(ctx, fsi); err != nil {
-			return ts, err
-		}
+	if err = db.insertMany(ctx, col, imports); err != nil {
+		return db.writeErr("persistFuelSales", err)
 	}
 
-	return ts, err
+	return err
 }
 
-func (db *MDB) compileFuelSales() (err error) {
+func (db *MDB) compileFuelSales(ctx context.Context) (err error) {
+
+	op := db.begin("compileFuelSales")
+	defer func() { op.end(err) }()
+
 	// Get list of station nodes to later match with
-	nodes, err := db.fetchStationNodes()
+	nodes, err := db.fetchStationNodes(ctx)
+	if err != nil {
+		return err
+	}
 
 	colIm := db.db.Collection(colFSImport)
 	colEx := db.db.Collection(colFSExport)
-	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second)
-	defer cancel()
 
+	// stations whose export docs failed to write do not stop the others, the first failure is returned
+	var writeErr error
 	for _, station := range nodes {
+		// stop between stations once the deadline has passed, rather than part way through one
+		if err = ctx.Err(); err != nil {
+			return wrapErr("compileFuelSales", err)
+		}
+		err = db.compileStation(ctx, op.span, colIm, colEx, station)
+		var bwe mongo.BulkWriteException
+		if errors.As(err, &bwe) {
+			if writeErr == nil {
+				writeErr = err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
 
-		pipeline := mongo.Pipeline{
-			{
-				primitive.E{
-					Key: "$match",
-					Value: bson.D{
-						primitive.E{
-							Key: "stationID",
-							Value: bson.D{
-								primitive.E{
-									Key:   "$in",
-									Value: station.Nodes,
-								},
+	err = writeErr
+	return err
+}
+
+// compileStation method compiles the imported sales for a station and its nodes into export docs
+func (db *MDB) compileStation(ctx context.Context, parent *tracing.Span, colIm, colEx *mongo.Collection, station model.StationNodes) (err error) {
+
+	span := parent.Start("compileStation")
+	span.Annotate("stationID", station.ID.Hex())
+	defer func() { span.End(err) }()
+
+	pipeline := mongo.Pipeline{
+		{
+			primitive.E{
+				Key: "$match",
+				Value: bson.D{
+					primitive.E{
+						Key: "stationID",
+						Value: bson.D{
+							primitive.E{
+								Key:   "$in",
+								Value: station.Nodes,
 							},
 						},
 					},
 				},
 			},
-			{
-				primitive.E{
-					Key: "$group",
-					Value: bson.D{
-						primitive.E{
-							Key: "_id",
-							Value: bson.D{
-								primitive.E{
-									Key:   "recordDate",
-									Value: "$recordDate",
-								},
-								primitive.E{
-									Key:   "importTS",
-									Value: "$importTS",
-								},
-							},
-						},
-						primitive.E{
-							Key: "avgFuelCost",
-							Value: bson.D{
-								primitive.E{
-									Key: "$avg",
-									Value: bson.D{
-										primitive.E{
-											Key: "$cond",
-											Value: bson.D{
-												primitive.E{
-													Key: "if",
-													Value: bson.D{
-														primitive.E{
-															Key:   "$gt",
-															Value: []interface{}{"$fuelCosts.fuel_1", 0},
-														},
-													},
-												},
-												primitive.E{
-													Key:   "then",
-													Value: "$fuelCosts.fuel_1",
-												},
-												primitive.E{
-													Key:   "else",
-													Value: nil,
-												},
-											},
-										},
-									},
-								},
-							},
-						},
-						primitive.E{
-							Key: "NL",
-							Value: bson.D{
-								primitive.E{
-									Key:   "$sum",
-									Value: "$fuelSales.NL",
-								},
-							},
-						},
-						primitive.E{
-							Key: "SNL",
-							Value: bson.D{
-								primitive.E{
-									Key:   "$sum",
-									Value: "$fuelSales.SNL",
-								},
-							},
-						},
-						primitive.E{
-							Key: "DSL",
-							Value: bson.D{
-								primitive.E{
-									Key:   "$sum",
-									Value: "$fuelSales.DSL",
-								},
-							},
-						},
-						primitive.E{
-							Key: "CDSL",
-							Value: bson.D{
-								primitive.E{
-									Key:   "$sum",
-									Value: "$fuelSales.CDSL",
-								},
-							},
-						},
-						primitive.E{
-							Key: "PROP",
-							Value: bson.D{
-								primitive.E{
-									Key:   "$sum",
-									Value: "$fuelSales.PROP",
-								},
+		},
+		compileGroup(bson.D{
+			primitive.E{
+				Key:   "recordDate",
+				Value: "$recordDate",
+			},
+			primitive.E{
+				Key:   "importTS",
+				Value: "$importTS",
+			},
+		}),
+		compileProjection(station.ID, 0),
+		{
+			primitive.E{
+				Key: "$sort",
+				Value: bson.D{
+					primitive.E{
+						Key:   "recordDate",
+						Value: 1,
+					},
+				},
+			},
+		},
+	}
+
+	cur, err := colIm.Aggregate(ctx, pipeline)
+	if err != nil {
+		return wrapErr("compileFuelSales", err)
+	}
+	defer cur.Close(ctx)
+
+	var docs []model.FuelSalesExport
+	if err := cur.All(ctx, &docs); err != nil {
+		return wrapErr("compileFuelSales", err)
+	}
+	span.Annotate("documents", len(docs))
+
+	// now we can insert/update fuel export docs
+	models := make([]mongo.WriteModel, 0, len(docs))
+	for _, doc := range docs {
+		doc.ID = fmt.Sprintf("%s-%s", strconv.Itoa(doc.RecordDate), doc.StationID.Hex())
+		doc.FuelMargins = model.ComputeMargins(doc.FuelSales, doc.FuelRevenue, doc.AvgFuelCosts)
+
+		filter := bson.D{
+			primitive.E{
+				Key:   "_id",
+				Value: doc.ID,
+			},
+		}
+		update := bson.D{
+			primitive.E{
+				Key:   "$set",
+				Value: doc,
+			},
+		}
+		models = append(models, mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update).SetUpsert(true))
+	}
+
+	if err = db.bulkWrite(ctx, colEx, models); err != nil {
+		db.logger().WithField(logging.Station, station.ID.Hex()).Errorf("Error upserting fuel sale exports. Error: %s", err)
+		return db.writeErr("compileFuelSales", err)
+	}
+
+	return err
+}
+
+func (db *MDB) removeImportedFuelSales(ctx context.Context) (res *mongo.DeleteResult, err error) {
+
+	op := db.begin("removeImportedFuelSales")
+	defer func() { op.end(err) }()
+
+	col := db.db.Collection(colFSImport)
+
+	res, err = col.DeleteMany(ctx, bson.D{})
+	if err != nil {
+		return nil, wrapErr("removeImportedFuelSales", err)
+	}
+
+	return res, err
+}
+
+// gradeCostAverages returns the $group accumulators used to average costs per grade
+// cost<GRADE> is the plain average of positive node costs, costLitres<GRADE> and
+// costVolume<GRADE> sum cost * litres and litres for nodes with a positive cost,
+// so the consolidated cost can be weighted by litres sold per node of the fuel it was paid for
+func gradeCostAverages() (fields bson.D) {
+
+	for _, grade := range model.FuelGrades {
+		costField := "$fuelCosts." + gradeCostFields[grade]
+		litreField := "$fuelSums." + gradeCostLitres[grade]
+		hasCost := bson.D{
+			primitive.E{
+				Key:   "$gt",
+				Value: []interface{}{costField, 0},
+			},
+		}
+
+		fields = append(fields,
+			primitive.E{
+				Key: "cost" + grade,
+				Value: bson.D{
+					primitive.E{
+						Key: "$avg",
+						Value: bson.D{
+							primitive.E{
+								Key:   "$cond",
+								Value: []interface{}{hasCost, costField, nil},
 							},
 						},
 					},
 				},
 			},
-			{
-				primitive.E{
-					Key: "$project",
-					Value: bson.D{
-						primitive.E{
-							Key:   "recordDate",
-							Value: "$_id.recordDate",
-						},
-						primitive.E{
-							Key:   "stationID",
-							Value: station.ID,
-						},
-						primitive.E{
-							Key:   "avgFuelCost",
-							Value: 1,
-						},
-						primitive.E{
-							Key:   "importTS",
-							Value: "$_id.importTS",
-						},
-						primitive.E{
-							Key:   "_id",
-							Value: 0,
-						},
-						primitive.E{
-							Key: "fuelSales",
-							Value: bson.D{
-								primitive.E{
-									Key:   "NL",
-									Value: "$NL",
-								},
-								primitive.E{
-									Key:   "SNL",
-									Value: "$SNL",
-								},
-								primitive.E{
-									Key:   "DSL",
-									Value: "$DSL",
-								},
-								primitive.E{
-									Key:   "CDSL",
-									Value: "$CDSL",
-								},
-								primitive.E{
-									Key:   "PROP",
-									Value: "$PROP",
+			primitive.E{
+				Key: "costLitres" + grade,
+				Value: bson.D{
+					primitive.E{
+						Key: "$sum",
+						Value: bson.D{
+							primitive.E{
+								Key: "$cond",
+								Value: []interface{}{
+									hasCost,
+									bson.D{primitive.E{Key: "$multiply", Value: []interface{}{costField, litreField}}},
+									0,
 								},
 							},
 						},
 					},
 				},
 			},
-			{
-				primitive.E{
-					Key: "$sort",
-					Value: bson.D{
-						primitive.E{
-							Key:   "recordDate",
-							Value: 1,
+			primitive.E{
+				Key: "costVolume" + grade,
+				Value: bson.D{
+					primitive.E{
+						Key: "$sum",
+						Value: bson.D{
+							primitive.E{
+								Key:   "$cond",
+								Value: []interface{}{hasCost, litreField, 0},
+							},
 						},
 					},
 				},
 			},
-		}
+		)
+	}
 
-		cur, err := colIm.Aggregate(ctx, pipeline)
-		if err != nil {
-			return err
-		}
-		defer cur.Close(ctx)
+	return fields
+}
 
-		var docs []model.FuelSalesExport
-		if err := cur.All(ctx, &docs); err != nil {
-			return err
-		}
+// gradeCostProjection returns the avgFuelCosts (volume weighted) and
+// unweightedFuelCosts maps for $project
+func gradeCostProjection() primitive.E {
 
-		// now we can insert/update fuel export doc
-		opts := options.Update().SetUpsert(true)
-		ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second)
-		defer cancel()
+	costs := bson.D{}
+	for _, grade := range model.FuelGrades {
+		costs = append(costs, primitive.E{
+			Key:   grade,
+			Value: weightedCost(grade),
+		})
+	}
 
-		for _, doc := range docs {
-			doc.ID = fmt.Sprintf("%s-%s", strconv.Itoa(doc.RecordDate), doc.StationID.Hex())
+	return primitive.E{
+		Key:   "avgFuelCosts",
+		Value: costs,
+	}
+}
 
-			filter := bson.D{
-				primitive.E{
-					Key:   "_id",
-					Value: doc.ID,
-				},
-			}
-			update := bson.D{
+// unweightedCostProjection returns the plain average of node costs per grade for $project
+func unweightedCostProjection() primitive.E {
+
+	costs := bson.D{}
+	for _, grade := range model.FuelGrades {
+		costs = append(costs, primitive.E{
+			Key:   grade,
+			Value: unweightedCost(grade),
+		})
+	}
+
+	return primitive.E{
+		Key:   "unweightedFuelCosts",
+		Value: costs,
+	}
+}
+
+// weightedCost returns the litre weighted cost expression for a grade, falling
+// back to the plain average when no litres were sold
+func weightedCost(grade string) bson.D {
+	return bson.D{
+		primitive.E{
+			Key: "$cond",
+			Value: []interface{}{
+				bson.D{primitive.E{Key: "$gt", Value: []interface{}{"$costVolume" + grade, 0}}},
+				bson.D{primitive.E{Key: "$divide", Value: []interface{}{"$costLitres" + grade, "$costVolume" + grade}}},
+				unweightedCost(grade),
+			},
+		},
+	}
+}
+
+// unweightedCost returns the plain average cost expression for a grade, 0 when there is no cost
+func unweightedCost(grade string) bson.D {
+	return bson.D{
+		primitive.E{
+			Key:   "$ifNull",
+			Value: []interface{}{"$cost" + grade, 0},
+		},
+	}
+}
+
+// gradeRevenueSums returns the $group accumulators summing revenue per grade
+func gradeRevenueSums() (fields bson.D) {
+
+	for _, grade := range model.FuelGrades {
+		fields = append(fields, primitive.E{
+			Key: "revenue" + grade,
+			Value: bson.D{
 				primitive.E{
-					Key:   "$set",
-					Value: doc,
+					Key:   "$sum",
+					Value: "$fuelRevenue." + grade,
 				},
-			}
-			_, err := colEx.UpdateOne(ctx, filter, update, opts)
-			if err != nil {
-				log.Errorf("Error upserting fuel sale export. Error: %s", err)
-				break
-			}
-		}
+			},
+		})
 	}
 
-	return err
+	return fields
 }
 
-func (db *MDB) removeImportedFuelSales() (res *mongo.DeleteResult, err error) {
+// gradeRevenueProjection returns the fuelRevenue document for $project
+func gradeRevenueProjection() primitive.E {
 
-	col := db.db.Collection(colFSImport)
-	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second)
-	defer cancel()
-
-	res, err = col.DeleteMany(ctx, bson.D{})
-	if err != nil {
-		return nil, err
+	revenue := bson.D{}
+	for _, grade := range model.FuelGrades {
+		revenue = append(revenue, primitive.E{
+			Key:   grade,
+			Value: "$revenue" + grade,
+		})
 	}
 
-	return res, err
+	return primitive.E{
+		Key:   "fuelRevenue",
+		Value: revenue,
+	}
 }
 
 // ==================== Propane methods ==================================== //
 
-func (db *MDB) fetchPropaneSales(req *model.Request) (docs []model.PropaneSale, err error) {
+func (db *MDB) fetchPropaneSales(ctx context.Context, req *model.Request) (docs []model.PropaneSale, err error) {
+
+	op := db.begin("fetchPropaneSales")
+	defer func() { op.fetched(len(docs)).end(err) }()
 
 	col := db.db.Collection(colFuelSales)
-	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second)
-	defer cancel()
 
 	propStationID, _ := primitive.ObjectIDFromHex(config.PropaneStationID)
 
@@ -777,25 +948,27 @@ func (db *MDB) fetchPropaneSales(req *model.Request) (docs []model.PropaneSale,
 
 	cur, err := col.Aggregate(ctx, pipeline)
 	if err != nil {
-		return nil, err
+		return nil, wrapErr("fetchPropaneSales", err)
 	}
 	defer cur.Close(ctx)
 
 	if err := cur.All(ctx, &docs); err != nil {
-		return nil, err
+		return nil, wrapErr("fetchPropaneSales", err)
 	}
 
 	return docs, err
 }
 
-func (db *MDB) persistPropaneSales(docs []model.PropaneSale) (ts int64, err error) {
+func (db *MDB) persistPropaneSales(ctx context.Context, docs []model.PropaneSale) (ts int64, err error) {
+
+	op := db.begin("persistPropaneSales")
+	defer func() { op.end(err) }()
 
 	col := db.db.Collection(colPSExport)
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
 
 	ts = time.Now().Unix()
 
+	exports := make([]interface{}, 0, len(docs))
 	for _, doc := range docs {
 		rdte, _ := strconv.Atoi(doc.RecordDate.Format(timeShortForm))
 		psi := &model.PropaneSaleExport{
@@ -804,10 +977,11 @@ func (db *MDB) persistPropaneSales(docs []model.PropaneSale) (ts int64, err erro
 			RecordDate: rdte,
 			TankID:     config.PropaneTankLookup(doc.DispenserID.Hex()),
 		}
+		exports = append(exports, psi)
+	}
 
-		if _, err := col.InsertOne(ctx, psi); err != nil {
-			return ts, err
-		}
+	if err = db.insertMany(ctx, col, exports); err != nil {
+		return ts, db.writeErr("persistPropaneSales", err)
 	}
 
 	return ts, err
@@ -815,30 +989,32 @@ func (db *MDB) persistPropaneSales(docs []model.PropaneSale) (ts int64, err erro
 
 // ==================== Fuel & Propane methods ============================= //
 
-func (db *MDB) fetchStationNodes() (nodes []model.StationNodes, err error) {
+func (db *MDB) fetchStationNodes(ctx context.Context) (nodes []model.StationNodes, err error) {
+
+	op := db.begin("fetchStationNodes")
+	defer func() { op.fetched(len(nodes)).end(err) }()
 
 	col := db.db.Collection(colStationNodes)
-	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second)
-	defer cancel()
 
 	cur, err := col.Find(ctx, bson.D{})
 	if err != nil {
-		return nodes, err
+		return nodes, wrapErr("fetchStationNodes", err)
 	}
 	defer cur.Close(ctx)
 
 	if err := cur.All(ctx, &nodes); err != nil {
-		return nodes, err
+		return nodes, wrapErr("fetchStationNodes", err)
 	}
 
 	return nodes, err
 }
 
-func (db *MDB) createImportLog(req *model.Request, ts int64) (res *mongo.InsertOneResult, err error) {
+func (db *MDB) createImportLog(ctx context.Context, req *model.Request, ts int64) (res *mongo.InsertOneResult, err error) {
+
+	op := db.begin("createImportLog")
+	defer func() { op.end(err) }()
 
 	col := db.db.Collection(colImportLog)
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
 
 	dteSt, _ := strconv.Atoi(req.DateStart.Format(timeShortForm))
 	dteEd, _ := strconv.Atoi(req.DateEnd.Format(timeShortForm))
@@ -852,7 +1028,7 @@ func (db *MDB) createImportLog(req *model.Request, ts int64) (res *mongo.InsertO
 
 	res, err = col.InsertOne(ctx, importlog)
 	if err != nil {
-		return nil, err
+		return nil, wrapErr("createImportLog", err)
 	}
 
 	return res, err
@@ -860,13 +1036,59 @@ func (db *MDB) createImportLog(req *model.Request, ts int64) (res *mongo.InsertO
 
 // ==================== DB Helper methods ==================== //
 
-// Close method
+// logger method returns the request logger
+func (db *MDB) logger() *log.Entry {
+	return logging.Or(db.Log)
+}
+
+// operation struct times and traces a single mongo operation
+type operation struct {
+	db    *MDB
+	docs  int
+	name  string
+	span  *tracing.Span
+	start time.Time
+}
+
+// begin method starts an operation, tracing it as a child of the MDB Trace span
+func (db *MDB) begin(name string) *operation {
+	return &operation{db: db, docs: -1, name: name, span: db.Trace.Start(name), start: time.Now()}
+}
+
+// fetched method sets the number of documents returned by the operation
+func (o *operation) fetched(n int) *operation {
+	o.docs = n
+	o.span.Annotate("documents", n)
+	return o
+}
+
+// end method records the latency, failures and documents fetched, and ends the span
+func (o *operation) end(err error) {
+
+	dims := map[string]string{"Operation": o.name}
+	o.db.Metrics.Since(dims, "MongoLatency", o.start)
+	if err != nil {
+		o.db.Metrics.Add(dims, "MongoFailures", 1)
+	}
+	if o.docs >= 0 {
+		o.db.Metrics.Add(dims, "DocumentsFetched", float64(o.docs))
+	}
+	o.span.End(err)
+}
+
+// Close method disconnects the client, unless it is shared by a Manager
 func (db *MDB) Close() {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+
+	if db.shared {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
 	defer cancel()
 	if err := db.client.Disconnect(ctx); err != nil {
-		panic(err)
+		db.logger().Errorf("Error disconnecting from MongoDB: %s", err)
+		return
 	}
 
-	log.Println("MongoDB Disconnected")
+	log.Info("MongoDB Disconnected")
 }
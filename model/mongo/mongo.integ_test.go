@@ -1,6 +1,7 @@
 package mongo
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"testing"
@@ -10,6 +11,8 @@ import (
 	"github.com/pulpfree/gsales-fs-export/model"
 	"github.com/pulpfree/gsales-fs-export/validators"
 	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 const (
@@ -24,6 +27,7 @@ const (
 // IntegSuite struct
 type IntegSuite struct {
 	cfg     *config.Config
+	ctx     context.Context
 	db      *MDB
 	fuelReq *model.Request
 	propReq *model.Request
@@ -42,7 +46,8 @@ func (s *IntegSuite) SetupTest() {
 	}
 	s.NoError(err)
 
-	s.db, err = NewDB(s.cfg.GetMongoConnectURL(), s.cfg.MongoDBName)
+	s.ctx = context.Background()
+	s.db, err = NewDB(s.ctx, s.cfg.GetMongoConnectURL(), s.cfg.MongoDBName)
 	if err != nil {
 		fmt.Printf("Error connecting to db: %s", err)
 		return
@@ -83,23 +88,25 @@ func TestIntegrationSuite(t *testing.T) {
 func (s *IntegSuite) TestCreateFuelSales() {
 	defer s.db.Close()
 
-	err := s.db.CreateFuelSales(s.fuelReq)
+	sales, err := s.db.CreateFuelSales(s.ctx, s.fuelReq)
 	s.NoError(err)
+	s.True(len(sales) > 0)
 }
 
 // TestCreatePropaneSales method
 func (s *IntegSuite) TestCreatePropaneSales() {
 	defer s.db.Close()
 
-	err := s.db.CreatePropaneSales(s.propReq)
+	sales, err := s.db.CreatePropaneSales(s.ctx, s.propReq)
 	s.NoError(err)
+	s.True(len(sales) > 0)
 }
 
 // TestFetchExportedFuelSales method
 func (s *IntegSuite) TestFetchExportedFuelSales() {
 	defer s.db.Close()
 
-	docs, err := s.db.FetchExportedFuelSales(s.fuelReq)
+	docs, err := s.db.FetchExportedFuelSales(s.ctx, s.fuelReq)
 	s.NoError(err)
 	s.True(len(docs) > 10)
 }
@@ -108,7 +115,7 @@ func (s *IntegSuite) TestFetchExportedFuelSales() {
 func (s *IntegSuite) TestFetchExportedPropaneSales() {
 	defer s.db.Close()
 
-	docs, err := s.db.FetchExportedPropaneSales(s.propReq)
+	docs, err := s.db.FetchExportedPropaneSales(s.ctx, s.propReq)
 	s.NoError(err)
 	s.True(len(docs) > 2)
 }
@@ -119,7 +126,7 @@ func (s *IntegSuite) TestFetchExportedPropaneSales() {
 func (s *IntegSuite) TestfetchFuelSales() {
 	defer s.db.Close()
 
-	docs, err := s.db.fetchFuelSales(s.fuelReq)
+	docs, err := s.db.fetchFuelSales(s.ctx, s.fuelReq)
 	s.NoError(err)
 	s.True(len(docs) > 10)
 }
@@ -128,7 +135,7 @@ func (s *IntegSuite) TestfetchFuelSales() {
 func (s *IntegSuite) TestfetchPropaneSales() {
 	defer s.db.Close()
 
-	docs, err := s.db.fetchPropaneSales(s.fuelReq)
+	docs, err := s.db.fetchPropaneSales(s.ctx, s.fuelReq)
 	s.NoError(err)
 	s.True(len(docs) > 2)
 }
@@ -137,20 +144,18 @@ func (s *IntegSuite) TestfetchPropaneSales() {
 func (s *IntegSuite) TestpersistFuelSales() {
 	defer s.db.Close()
 
-	docs, err := s.db.fetchFuelSales(s.fuelReq)
+	docs, err := s.db.fetchFuelSales(s.ctx, s.fuelReq)
 	s.NoError(err)
 
-	ts1 := time.Now().Unix()
-	ts2, err := s.db.persistFuelSales(docs)
+	err = s.db.persistFuelSales(s.ctx, docs, time.Now().Unix())
 	s.NoError(err)
-	s.True(ts1 == ts2)
 }
 
 // TestfetchStationNodes method
 func (s *IntegSuite) TestfetchStationNodes() {
 	defer s.db.Close()
 
-	nodes, err := s.db.fetchStationNodes()
+	nodes, err := s.db.fetchStationNodes(s.ctx)
 	s.NoError(err)
 	s.True(len(nodes) > 10)
 }
@@ -160,7 +165,7 @@ func (s *IntegSuite) TestfetchStationNodes() {
 func (s *IntegSuite) TestremoveImportedFuelSales() {
 	defer s.db.Close()
 
-	res, err := s.db.removeImportedFuelSales()
+	res, err := s.db.removeImportedFuelSales(s.ctx)
 	s.NoError(err)
 	s.True(res.DeletedCount > 10)
 }
@@ -169,19 +174,19 @@ func (s *IntegSuite) TestremoveImportedFuelSales() {
 func (s *IntegSuite) TestcompileFuelSales() {
 	defer s.db.Close()
 
-	_, err := s.db.removeImportedFuelSales()
+	_, err := s.db.removeImportedFuelSales(s.ctx)
 	s.NoError(err)
 
-	docs, err := s.db.fetchFuelSales(s.fuelReq)
+	docs, err := s.db.fetchFuelSales(s.ctx, s.fuelReq)
 	s.NoError(err)
 
-	_, err = s.db.persistFuelSales(docs)
+	err = s.db.persistFuelSales(s.ctx, docs, time.Now().Unix())
 	s.NoError(err)
 
-	err = s.db.compileFuelSales()
+	err = s.db.compileFuelSales(s.ctx)
 	s.NoError(err)
 
-	_, err = s.db.removeImportedFuelSales()
+	_, err = s.db.removeImportedFuelSales(s.ctx)
 	s.NoError(err)
 }
 
@@ -190,7 +195,7 @@ func (s *IntegSuite) TestcreateImportLog() {
 	defer s.db.Close()
 
 	ts := time.Now().Unix()
-	_, err := s.db.createImportLog(s.fuelReq, ts)
+	_, err := s.db.createImportLog(s.ctx, s.fuelReq, ts)
 	s.NoError(err)
 }
 
@@ -198,10 +203,77 @@ func (s *IntegSuite) TestcreateImportLog() {
 func (s *IntegSuite) TestpersistPropaneSales() {
 	defer s.db.Close()
 
-	docs, err := s.db.fetchPropaneSales(s.fuelReq)
+	docs, err := s.db.fetchPropaneSales(s.ctx, s.fuelReq)
 	s.NoError(err)
 	fmt.Printf("docs: %+v\n", docs[0])
 
-	ts, err := s.db.persistPropaneSales(docs)
+	ts, err := s.db.persistPropaneSales(s.ctx, docs)
 	fmt.Printf("ts: %+v\n", ts)
 }
+
+// TestcompileFuelSalesWeighted method
+// seeds the import collection with mixed node volumes and checks the consolidated costs
+func (s *IntegSuite) TestcompileFuelSalesWeighted() {
+	defer s.db.Close()
+
+	nodes, err := s.db.fetchStationNodes(s.ctx)
+	s.NoError(err)
+
+	var station model.StationNodes
+	for _, n := range nodes {
+		if len(n.Nodes) > 1 {
+			station = n
+			break
+		}
+	}
+	if len(station.Nodes) < 2 {
+		s.T().Skip("no station with multiple nodes found")
+	}
+
+	_, err = s.db.removeImportedFuelSales(s.ctx)
+	s.NoError(err)
+
+	ts := time.Now().Unix()
+	recordDate := 19000101
+	seed := []interface{}{
+		&model.FuelSalesImport{
+			FuelCosts:  &model.FuelCosts{Fuel1: 1.00, Fuel4: 1.20},
+			FuelSales:  &model.FuelSales{NL: 9000, DSL: 100},
+			FuelSums:   &model.FuelSums{Fuel1: 9000, Fuel4: 100},
+			ImportTS:   ts,
+			RecordDate: recordDate,
+			StationID:  station.Nodes[0],
+		},
+		&model.FuelSalesImport{
+			FuelCosts:  &model.FuelCosts{Fuel1: 2.00, Fuel4: 1.40},
+			FuelSales:  &model.FuelSales{NL: 1000, DSL: 300},
+			FuelSums:   &model.FuelSums{Fuel1: 1000, Fuel4: 300},
+			ImportTS:   ts,
+			RecordDate: recordDate,
+			StationID:  station.Nodes[1],
+		},
+	}
+	_, err = s.db.db.Collection(colFSImport).InsertMany(s.ctx, seed)
+	s.NoError(err)
+
+	err = s.db.compileFuelSales(s.ctx)
+	s.NoError(err)
+
+	var doc model.FuelSalesExport
+	id := fmt.Sprintf("%d-%s", recordDate, station.ID.Hex())
+	colEx := s.db.db.Collection(colFSExport)
+	err = colEx.FindOne(s.ctx, bson.D{primitive.E{Key: "_id", Value: id}}).Decode(&doc)
+	s.NoError(err)
+
+	s.InDelta(1.10, doc.AvgFuelCost, 0.0001)
+	s.InDelta(1.10, doc.AvgFuelCosts[model.GradeNL], 0.0001)
+	s.InDelta(1.35, doc.AvgFuelCosts[model.GradeDSL], 0.0001)
+	s.InDelta(1.50, doc.UnweightedFuelCosts[model.GradeNL], 0.0001)
+	s.InDelta(1.30, doc.UnweightedFuelCosts[model.GradeDSL], 0.0001)
+	s.Equal(0.0, doc.AvgFuelCosts[model.GradeCDSL])
+
+	_, err = colEx.DeleteOne(s.ctx, bson.D{primitive.E{Key: "_id", Value: id}})
+	s.NoError(err)
+	_, err = s.db.removeImportedFuelSales(s.ctx)
+	s.NoError(err)
+}
@@ -11,30 +11,57 @@ type DnStation struct {
 
 // DnFuelSales struct
 type DnFuelSales struct {
-	AvgFuelCost float64    `json:"AvgFuelCost"`
-	Date        int        `json:"Date"`
-	ImportTS    int64      `json:"ImportTS"`
-	Sales       *FuelSales `json:"Sales"`
-	StationID   string     `json:"StationID"`
-	YearWeek    int        `json:"YearWeek"`
+	AvgFuelCost      float64                `json:"AvgFuelCost"`
+	Date             int                    `json:"Date"`
+	FuelPrices       map[string]float64     `json:"FuelPrices"`
+	ImportTS         int64                  `json:"ImportTS"`
+	Margins          map[string]*FuelMargin `json:"Margins"`
+	Placeholder      bool                   `json:"Placeholder,omitempty"`
+	Sales            *FuelSales             `json:"Sales"`
+	StationID        string                 `json:"StationID"`
+	UnweightedPrices map[string]float64     `json:"UnweightedPrices"`
+	YearWeek         int                    `json:"YearWeek"`
 }
 
 // DnFuelPrice struct
+// DateGrade (YYYYMMDD#GRADE) is the sort key, giving one item per station, day and grade
 type DnFuelPrice struct {
 	Date      int     `json:"Date"`
+	DateGrade string  `json:"DateGrade"`
+	Grade     string  `json:"Grade"`
 	Price     float64 `json:"Price"`
 	StationID string  `json:"StationID"`
 	YearWeek  int     `json:"YearWeek"`
 }
 
+// DnFuelMargin struct
+// DateGrade (YYYYMMDD#GRADE) is the sort key, giving one item per station, day and grade
+type DnFuelMargin struct {
+	COGS      float64 `json:"COGS"`
+	Cost      float64 `json:"Cost"`
+	Date      int     `json:"Date"`
+	DateGrade string  `json:"DateGrade"`
+	Grade     string  `json:"Grade"`
+	Litres    float64 `json:"Litres"`
+	Margin    float64 `json:"Margin"`
+	Revenue   float64 `json:"Revenue"`
+	StationID string  `json:"StationID"`
+	YearWeek  int     `json:"YearWeek"`
+}
+
 // DnImportRes struct
 type DnImportRes struct {
-	DateEnd        string `json:"DateEnd"`
-	DateStart      string `json:"DateStart"`
-	ImportDate     string `json:"ImportDate"`
-	ImportTS       int64  `json:"ImportTS"`
-	ImportType     string `json:"ImportType"`
-	RecordQuantity int    `json:"RecordQty"`
+	ArchivePath    string       `json:"ArchivePath,omitempty"`
+	DateEnd        string       `json:"DateEnd"`
+	DateStart      string       `json:"DateStart"`
+	Findings       []*Finding   `json:"Findings,omitempty"`
+	Gaps           []*Gap       `json:"Gaps,omitempty"`
+	ImportDate     string       `json:"ImportDate"`
+	ImportTS       int64        `json:"ImportTS"`
+	ImportType     string       `json:"ImportType"`
+	Progress       *Progress    `json:"Progress,omitempty"`
+	RecordQuantity int          `json:"RecordQty"`
+	Status         ImportStatus `json:"Status,omitempty"`
 }
 
 // DnPropaneSales struct
@@ -18,6 +18,12 @@ type FuelCosts struct {
 	Fuel6 float64 `bson:"fuel_6" json:"fuel6"`
 }
 
+// FuelDollars type holds sales dollars per fuel, shaped as the FuelSums litres
+type FuelDollars FuelSums
+
+// FuelRevenue type holds sales dollars per grade, shaped as the FuelSales litres
+type FuelRevenue FuelSales
+
 // FuelSales struct
 type FuelSales struct {
 	NL   float64 `bson:"NL" json:"NL"`
@@ -28,24 +34,31 @@ type FuelSales struct {
 }
 
 // FuelSalesExport struct
+// AvgFuelCosts are weighted by litres sold per station node, UnweightedFuelCosts are a plain average
 type FuelSalesExport struct {
-	ID          string             `bson:"_id"`
-	AvgFuelCost float64            `bson:"avgFuelCost"`
-	FuelSales   *FuelSales         `bson:"fuelSales"`
-	ImportTS    int64              `bson:"importTS"`
-	RecordDate  int                `bson:"recordDate"`
-	StationID   primitive.ObjectID `bson:"stationID" json:"stationID"`
+	ID                  string                 `bson:"_id"`
+	AvgFuelCost         float64                `bson:"avgFuelCost"`
+	AvgFuelCosts        map[string]float64     `bson:"avgFuelCosts"`
+	FuelMargins         map[string]*FuelMargin `bson:"fuelMargins"`
+	FuelRevenue         *FuelRevenue           `bson:"fuelRevenue"`
+	FuelSales           *FuelSales             `bson:"fuelSales"`
+	ImportTS            int64                  `bson:"importTS"`
+	Placeholder         bool                   `bson:"placeholder,omitempty"`
+	RecordDate          int                    `bson:"recordDate"`
+	StationID           primitive.ObjectID     `bson:"stationID" json:"stationID"`
+	UnweightedFuelCosts map[string]float64     `bson:"unweightedFuelCosts"`
 }
 
 // FuelSalesImport struct
 type FuelSalesImport struct {
-	FuelCosts  *FuelCosts         `bson:"fuelCosts"`
-	FuelSales  *FuelSales         `bson:"fuelSales"`
-	FuelSums   *FuelSums          `bson:"fuelSums"`
-	ImportTS   int64              `bson:"importTS"`
-	RecordDate int                `bson:"recordDate"`
-	StationID  primitive.ObjectID `bson:"stationID" json:"stationID"`
-	Status     string             `bson:"status"`
+	FuelCosts   *FuelCosts         `bson:"fuelCosts"`
+	FuelRevenue *FuelRevenue       `bson:"fuelRevenue"`
+	FuelSales   *FuelSales         `bson:"fuelSales"`
+	FuelSums    *FuelSums          `bson:"fuelSums"`
+	ImportTS    int64              `bson:"importTS"`
+	RecordDate  int                `bson:"recordDate"`
+	StationID   primitive.ObjectID `bson:"stationID" json:"stationID"`
+	Status      string             `bson:"status"`
 }
 
 // FuelSums struct
@@ -90,13 +103,14 @@ type StationNodes struct {
 
 // StationSales struct
 type StationSales struct {
-	RecordDate time.Time          `bson:"recordDate" json:"recordDate"`
-	StationID  primitive.ObjectID `bson:"stationID" json:"stationID"`
-	Fuel1      float64            `bson:"fuel1" json:"fuel1"`
-	Fuel2      float64            `bson:"fuel2" json:"fuel2"`
-	Fuel3      float64            `bson:"fuel3" json:"fuel3"`
-	Fuel4      float64            `bson:"fuel4" json:"fuel4"`
-	Fuel5      float64            `bson:"fuel5" json:"fuel5"`
-	Fuel6      float64            `bson:"fuel6" json:"fuel6"`
-	FuelCosts  *FuelCosts         `bson:"fuelCosts"`
+	RecordDate  time.Time          `bson:"recordDate" json:"recordDate"`
+	StationID   primitive.ObjectID `bson:"stationID" json:"stationID"`
+	Fuel1       float64            `bson:"fuel1" json:"fuel1"`
+	Fuel2       float64            `bson:"fuel2" json:"fuel2"`
+	Fuel3       float64            `bson:"fuel3" json:"fuel3"`
+	Fuel4       float64            `bson:"fuel4" json:"fuel4"`
+	Fuel5       float64            `bson:"fuel5" json:"fuel5"`
+	Fuel6       float64            `bson:"fuel6" json:"fuel6"`
+	FuelCosts   *FuelCosts         `bson:"fuelCosts"`
+	FuelDollars *FuelDollars       `bson:"fuelDollars"`
 }
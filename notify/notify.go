@@ -0,0 +1,120 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pulpfree/gsales-fs-export/config"
+	"github.com/pulpfree/gsales-fs-export/model"
+)
+
+const timeForm = "2006-01-02"
+
+// Status constants
+const (
+	StatusSuccess = "success"
+	StatusFailure = "failure"
+)
+
+// Summary struct is posted to each notifier when an export finishes
+type Summary struct {
+	DateEnd        string   `json:"DateEnd"`
+	DateStart      string   `json:"DateStart"`
+	DurationMs     int64    `json:"DurationMs"`
+	Error          string   `json:"Error,omitempty"`
+	ErrorCode      string   `json:"ErrorCode,omitempty"`
+	ImportTS       int64    `json:"ImportTS,omitempty"`
+	ImportType     string   `json:"ImportType"`
+	RecordQuantity int      `json:"RecordQty"`
+	Stage          string   `json:"Stage"`
+	Status         string   `json:"Status"`
+	Warnings       []string `json:"Warnings,omitempty"`
+}
+
+// Notifier interface
+type Notifier interface {
+	Notify(s *Summary) error
+}
+
+// NewSummary function builds the summary from the request and the export result
+// res may be nil when the export failed before any work was done
+func NewSummary(stage config.StageEnvironment, req *model.Request, res *model.DnImportRes, dur time.Duration, err error) *Summary {
+
+	s := &Summary{
+		DateEnd:    req.DateEnd.Format(timeForm),
+		DateStart:  req.DateStart.Format(timeForm),
+		DurationMs: dur.Milliseconds(),
+		ImportType: string(req.ExportType),
+		Stage:      string(stage),
+		Status:     StatusSuccess,
+	}
+
+	if res != nil {
+		s.ImportTS = res.ImportTS
+		s.RecordQuantity = res.RecordQuantity
+		for _, f := range res.Findings {
+			s.Warnings = append(s.Warnings, fmt.Sprintf("%s: %s", f.Rule, f.Message))
+		}
+		if len(res.Gaps) > 0 {
+			s.Warnings = append(s.Warnings, fmt.Sprintf("%d station days without sales", len(res.Gaps)))
+		}
+	}
+
+	if err != nil {
+		s.Status = StatusFailure
+		s.Error = err.Error()
+		var mErr *model.Error
+		if errors.As(err, &mErr) {
+			s.ErrorCode = mErr.Code
+		}
+	}
+
+	return s
+}
+
+// Subject method returns a one line description of the summary
+func (s *Summary) Subject() string {
+	return fmt.Sprintf("[%s] %s export %s to %s: %s", s.Stage, s.ImportType, s.DateStart, s.DateEnd, s.Status)
+}
+
+// FromConfig function returns a notifier for each destination configured for the stage
+// Returns nil when no destination is set
+func FromConfig(cfg *config.Config) (Notifier, error) {
+
+	var ns Multi
+	if cfg.NotifyTopicArn != "" {
+		n, err := NewSNS(cfg.NotifyTopicArn, cfg.AWSRegion)
+		if err != nil {
+			return nil, err
+		}
+		ns = append(ns, n)
+	}
+	if cfg.NotifyWebhookURL != "" {
+		ns = append(ns, NewWebhook(cfg.NotifyWebhookURL, cfg.NotifyWebhookSecret, cfg.NotifyTimeout))
+	}
+
+	if len(ns) == 0 {
+		return nil, nil
+	}
+	return ns, nil
+}
+
+// Multi type sends the summary to every notifier, returning the combined errors
+type Multi []Notifier
+
+// Notify method
+func (m Multi) Notify(s *Summary) error {
+
+	var msgs []string
+	for _, n := range m {
+		if err := n.Notify(s); err != nil {
+			msgs = append(msgs, err.Error())
+		}
+	}
+	if len(msgs) > 0 {
+		return errors.New(strings.Join(msgs, "; "))
+	}
+	return nil
+}
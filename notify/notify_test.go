@@ -0,0 +1,101 @@
+package notify
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+	"github.com/pulpfree/gsales-fs-export/config"
+	"github.com/pulpfree/gsales-fs-export/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSNS struct {
+	snsiface.SNSAPI
+	inputs []*sns.PublishInput
+}
+
+func (f *fakeSNS) Publish(in *sns.PublishInput) (*sns.PublishOutput, error) {
+	f.inputs = append(f.inputs, in)
+	return &sns.PublishOutput{}, nil
+}
+
+func testRequest() *model.Request {
+	return &model.Request{
+		DateEnd:    time.Date(2023, 6, 7, 0, 0, 0, 0, time.UTC),
+		DateStart:  time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC),
+		ExportType: model.FuelType,
+	}
+}
+
+// TestNewSummary function
+func TestNewSummary(t *testing.T) {
+
+	res := &model.DnImportRes{
+		Findings:       []*model.Finding{{Message: "Volume spike", Rule: "volume_spike", Severity: model.SeverityWarn}},
+		Gaps:           []*model.Gap{{Date: 20230602}},
+		ImportTS:       1685836800,
+		RecordQuantity: 42,
+	}
+
+	s := NewSummary(config.TestEnv, testRequest(), res, 1500*time.Millisecond, nil)
+	assert.Equal(t, StatusSuccess, s.Status)
+	assert.Equal(t, "2023-06-01", s.DateStart)
+	assert.Equal(t, int64(1500), s.DurationMs)
+	assert.Equal(t, 42, s.RecordQuantity)
+	assert.Equal(t, []string{"volume_spike: Volume spike", "1 station days without sales"}, s.Warnings)
+
+	err := (&model.Error{Code: "fuel_sales_not_found", Msg: "Error fetching exported fuel sales"}).Wrap("fuel", nil)
+	s = NewSummary(config.TestEnv, testRequest(), nil, time.Second, err)
+	assert.Equal(t, StatusFailure, s.Status)
+	assert.Equal(t, "fuel_sales_not_found", s.ErrorCode)
+	assert.Equal(t, "[test] fuel export 2023-06-01 to 2023-06-07: failure", s.Subject())
+}
+
+// TestWebhook function
+func TestWebhook(t *testing.T) {
+
+	var got Summary
+	var sig string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		sig = r.Header.Get(SignatureHeader)
+		json.Unmarshal(body, &got)
+		assert.Equal(t, "sha256="+Sign("secret", body), sig)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	s := NewSummary(config.TestEnv, testRequest(), &model.DnImportRes{RecordQuantity: 7}, time.Second, nil)
+	err := NewWebhook(srv.URL, "secret", time.Second).Notify(s)
+	require.NoError(t, err)
+	assert.Equal(t, 7, got.RecordQuantity)
+	assert.NotEmpty(t, sig)
+
+	// Non 2xx responses are errors
+	fail := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer fail.Close()
+	err = NewWebhook(fail.URL, "", 0).Notify(s)
+	assert.Error(t, err)
+}
+
+// TestSNS function
+func TestSNS(t *testing.T) {
+
+	svc := &fakeSNS{}
+	n := &SNS{TopicArn: "arn:aws:sns:ca-central-1:123456789012:exports", svc: svc}
+
+	s := NewSummary(config.TestEnv, testRequest(), &model.DnImportRes{RecordQuantity: 7}, time.Second, nil)
+	require.NoError(t, Multi{n}.Notify(s))
+	require.Len(t, svc.inputs, 1)
+	assert.Equal(t, s.Subject(), *svc.inputs[0].Subject)
+	assert.Equal(t, StatusSuccess, *svc.inputs[0].MessageAttributes["status"].StringValue)
+}
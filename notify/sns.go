@@ -0,0 +1,57 @@
+package notify
+
+import (
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+)
+
+// maxSubject is the SNS limit on message subjects
+const maxSubject = 100
+
+// SNS struct publishes summaries to a topic
+type SNS struct {
+	TopicArn string
+	svc      snsiface.SNSAPI
+}
+
+// NewSNS function
+func NewSNS(topicArn, region string) (*SNS, error) {
+
+	sess, err := session.NewSession(&aws.Config{
+		Region: aws.String(region),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &SNS{TopicArn: topicArn, svc: sns.New(sess)}, nil
+}
+
+// Notify method
+func (n *SNS) Notify(s *Summary) error {
+
+	body, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	subject := s.Subject()
+	if len(subject) > maxSubject {
+		subject = subject[:maxSubject]
+	}
+
+	_, err = n.svc.Publish(&sns.PublishInput{
+		Message: aws.String(string(body)),
+		MessageAttributes: map[string]*sns.MessageAttributeValue{
+			"status": {DataType: aws.String("String"), StringValue: aws.String(s.Status)},
+			"type":   {DataType: aws.String("String"), StringValue: aws.String(s.ImportType)},
+		},
+		Subject:  aws.String(subject),
+		TopicArn: aws.String(n.TopicArn),
+	})
+	return err
+}
@@ -0,0 +1,76 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// SignatureHeader is set on webhook requests when a secret is configured
+// The value is the hex encoded HMAC-SHA256 of the body, prefixed with sha256=
+const SignatureHeader = "X-Export-Signature"
+
+const defaultTimeout = 10 * time.Second
+
+// Webhook struct posts summaries as json to an https endpoint
+type Webhook struct {
+	Client *http.Client
+	Secret string
+	URL    string
+}
+
+// NewWebhook function, a zero timeout uses the default of 10 seconds
+func NewWebhook(url, secret string, timeout time.Duration) *Webhook {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	return &Webhook{
+		Client: &http.Client{Timeout: timeout},
+		Secret: secret,
+		URL:    url,
+	}
+}
+
+// Notify method
+func (n *Webhook) Notify(s *Summary) error {
+
+	body, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.Secret != "" {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(n.Secret, body))
+	}
+
+	res, err := n.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(ioutil.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("webhook %s responded with status %d", n.URL, res.StatusCode)
+	}
+	return nil
+}
+
+// Sign function returns the hex encoded HMAC-SHA256 of body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
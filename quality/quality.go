@@ -0,0 +1,86 @@
+package quality
+
+import (
+	"github.com/pulpfree/gsales-fs-export/model"
+)
+
+// Severity constants
+const (
+	SeverityWarn  = model.SeverityWarn
+	SeverityBlock = model.SeverityBlock
+)
+
+// Rule interface, rules that don't apply to an export type return nil
+type Rule interface {
+	Name() string
+	CheckFuel(sales []*model.FuelSalesExport) []*model.Finding
+	CheckPropane(sales []*model.PropaneSaleExport) []*model.Finding
+}
+
+// Engine struct runs a set of rules against exported sales
+type Engine struct {
+	rules []Rule
+}
+
+// Report struct
+type Report struct {
+	Findings []*model.Finding
+}
+
+// New function
+func New(rules ...Rule) *Engine {
+	return &Engine{rules: rules}
+}
+
+// Default function returns an engine with the default rule set
+func Default() *Engine {
+	return New(DefaultRules()...)
+}
+
+// DefaultRules function
+func DefaultRules() []Rule {
+	return []Rule{
+		&NonNegativeVolumes{Severity: SeverityBlock},
+		&ZeroSales{Severity: SeverityWarn},
+		&CostRange{Severity: SeverityBlock, Ranges: DefaultCostRanges()},
+		&VolumeSpike{Severity: SeverityWarn, StdDevs: 3, MinDays: 3, Window: 7, MinChange: 0.25},
+	}
+}
+
+// Fuel method
+func (e *Engine) Fuel(sales []*model.FuelSalesExport) *Report {
+	r := &Report{}
+	for _, rule := range e.rules {
+		r.Findings = append(r.Findings, rule.CheckFuel(sales)...)
+	}
+	return r
+}
+
+// Propane method
+func (e *Engine) Propane(sales []*model.PropaneSaleExport) *Report {
+	r := &Report{}
+	for _, rule := range e.rules {
+		r.Findings = append(r.Findings, rule.CheckPropane(sales)...)
+	}
+	return r
+}
+
+// Blocked method returns true if any finding has a block severity
+func (r *Report) Blocked() bool {
+	for _, f := range r.Findings {
+		if f.Severity == SeverityBlock {
+			return true
+		}
+	}
+	return false
+}
+
+// Blocking method returns the findings with a block severity
+func (r *Report) Blocking() (findings []*model.Finding) {
+	for _, f := range r.Findings {
+		if f.Severity == SeverityBlock {
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
@@ -0,0 +1,150 @@
+package quality
+
+import (
+	"testing"
+
+	"github.com/pulpfree/gsales-fs-export/model"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func fuelSale(date int, nl float64, nlCost float64) *model.FuelSalesExport {
+	stationID, _ := primitive.ObjectIDFromHex("56cf1815982d82b0f3000001")
+	return &model.FuelSalesExport{
+		AvgFuelCosts: map[string]float64{model.GradeNL: nlCost},
+		FuelSales:    &model.FuelSales{NL: nl, DSL: 500},
+		RecordDate:   date,
+		StationID:    stationID,
+	}
+}
+
+// TestNonNegativeVolumes function
+func TestNonNegativeVolumes(t *testing.T) {
+
+	r := &NonNegativeVolumes{Severity: SeverityBlock}
+
+	findings := r.CheckFuel([]*model.FuelSalesExport{fuelSale(20230601, 1000, 1.1), fuelSale(20230602, -10, 1.1)})
+	assert.Len(t, findings, 1)
+	assert.Equal(t, 20230602, findings[0].Date)
+	assert.Equal(t, model.GradeNL, findings[0].Grade)
+
+	findings = r.CheckPropane([]*model.PropaneSaleExport{{RecordDate: 20230601, Litres: -5, TankID: 475}})
+	assert.Len(t, findings, 1)
+	assert.Equal(t, 475, findings[0].TankID)
+}
+
+// TestCostRange function
+func TestCostRange(t *testing.T) {
+
+	r := &CostRange{Severity: SeverityBlock, Ranges: DefaultCostRanges()}
+
+	findings := r.CheckFuel([]*model.FuelSalesExport{
+		fuelSale(20230601, 1000, 1.1),
+		fuelSale(20230602, 1000, 110),
+		fuelSale(20230603, 1000, 0),
+	})
+	assert.Len(t, findings, 1)
+	assert.Equal(t, 20230602, findings[0].Date)
+}
+
+// TestVolumeSpike function
+func TestVolumeSpike(t *testing.T) {
+
+	r := &VolumeSpike{Severity: SeverityWarn, StdDevs: 2, MinDays: 5}
+
+	var sales []*model.FuelSalesExport
+	for d := 1; d <= 9; d++ {
+		sales = append(sales, fuelSale(20230600+d, 1000+float64(d), 1.1))
+	}
+	sales = append(sales, fuelSale(20230610, 9000, 1.1))
+
+	findings := r.CheckFuel(sales)
+	assert.Len(t, findings, 1)
+	assert.Equal(t, 20230610, findings[0].Date)
+	assert.Equal(t, model.GradeNL, findings[0].Grade)
+
+	// too few preceding days to judge
+	assert.Empty(t, r.CheckFuel(sales[6:]))
+}
+
+// TestVolumeSpikeShortSeries function checks a spike is found in a range of a few days
+// and judged against the preceding days only, in date order
+func TestVolumeSpikeShortSeries(t *testing.T) {
+
+	r := &VolumeSpike{Severity: SeverityWarn, StdDevs: 3, MinDays: 3}
+
+	sales := []*model.FuelSalesExport{
+		fuelSale(20230605, 5000, 1.1),
+		fuelSale(20230601, 1000, 1.1),
+		fuelSale(20230602, 1010, 1.1),
+		fuelSale(20230603, 990, 1.1),
+		fuelSale(20230604, 1005, 1.1),
+		fuelSale(20230606, 1000, 1.1),
+	}
+
+	findings := r.CheckFuel(sales)
+	if assert.Len(t, findings, 1) {
+		assert.Equal(t, 20230605, findings[0].Date)
+		assert.Equal(t, 5000.0, findings[0].Value)
+	}
+}
+
+// TestVolumeSpikeFlatWindow function checks a small change after a flat window is not a spike
+func TestVolumeSpikeFlatWindow(t *testing.T) {
+
+	r := &VolumeSpike{Severity: SeverityWarn, StdDevs: 3, MinDays: 3}
+
+	sales := []*model.FuelSalesExport{
+		fuelSale(20230601, 1000, 1.1),
+		fuelSale(20230602, 1000, 1.1),
+		fuelSale(20230603, 1000, 1.1),
+		fuelSale(20230604, 1010, 1.1),
+		fuelSale(20230605, 2000, 1.1),
+	}
+
+	findings := r.CheckFuel(sales)
+	if assert.Len(t, findings, 1) {
+		assert.Equal(t, 20230605, findings[0].Date)
+	}
+}
+
+// TestVolumeSpikePlaceholder function checks a gap placeholder in the window is neither
+// flagged nor counted in the preceding days
+func TestVolumeSpikePlaceholder(t *testing.T) {
+
+	r := &VolumeSpike{Severity: SeverityWarn, StdDevs: 3, MinDays: 3}
+
+	gap := fuelSale(20230604, 0, 0)
+	gap.FuelSales = &model.FuelSales{}
+	gap.Placeholder = true
+
+	sales := []*model.FuelSalesExport{
+		fuelSale(20230601, 1000, 1.1),
+		fuelSale(20230602, 1010, 1.1),
+		fuelSale(20230603, 990, 1.1),
+		gap,
+		fuelSale(20230605, 1005, 1.1),
+		fuelSale(20230606, 995, 1.1),
+	}
+
+	assert.Empty(t, r.CheckFuel(sales))
+}
+
+// TestEngine function
+func TestEngine(t *testing.T) {
+
+	sales := []*model.FuelSalesExport{
+		fuelSale(20230601, 1000, 1.1),
+		{FuelSales: &model.FuelSales{}, RecordDate: 20230602},
+	}
+
+	report := Default().Fuel(sales)
+	assert.Len(t, report.Findings, 1)
+	assert.Equal(t, "zero_sales", report.Findings[0].Rule)
+	assert.False(t, report.Blocked())
+
+	sales = append(sales, fuelSale(20230603, -1, 1.1))
+	report = Default().Fuel(sales)
+	assert.True(t, report.Blocked())
+	assert.Len(t, report.Blocking(), 1)
+}
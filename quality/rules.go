@@ -0,0 +1,318 @@
+package quality
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/pulpfree/gsales-fs-export/model"
+)
+
+// CostBounds struct
+type CostBounds struct {
+	Min float64
+	Max float64
+}
+
+// DefaultCostRanges function returns the accepted cost per litre range for each grade
+func DefaultCostRanges() map[string]CostBounds {
+	return map[string]CostBounds{
+		model.GradeNL:   {Min: 0.30, Max: 3.00},
+		model.GradeSNL:  {Min: 0.30, Max: 3.50},
+		model.GradeDSL:  {Min: 0.30, Max: 3.50},
+		model.GradeCDSL: {Min: 0.30, Max: 3.50},
+		model.GradePROP: {Min: 0.20, Max: 2.50},
+	}
+}
+
+// ==================== NonNegativeVolumes ==================== //
+
+// NonNegativeVolumes rule flags negative litres
+type NonNegativeVolumes struct {
+	Severity model.Severity
+}
+
+// Name method
+func (r *NonNegativeVolumes) Name() string { return "non_negative_volumes" }
+
+// CheckFuel method
+func (r *NonNegativeVolumes) CheckFuel(sales []*model.FuelSalesExport) (findings []*model.Finding) {
+	for _, s := range sales {
+		for _, grade := range model.FuelGrades {
+			if v := s.FuelSales.Grade(grade); v < 0 {
+				findings = append(findings, &model.Finding{
+					Date:      s.RecordDate,
+					Grade:     grade,
+					Message:   fmt.Sprintf("Negative %s volume of %.2f litres", grade, v),
+					Rule:      r.Name(),
+					Severity:  r.Severity,
+					StationID: s.StationID.Hex(),
+					Value:     v,
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// CheckPropane method
+func (r *NonNegativeVolumes) CheckPropane(sales []*model.PropaneSaleExport) (findings []*model.Finding) {
+	for _, s := range sales {
+		if s.Litres < 0 {
+			findings = append(findings, &model.Finding{
+				Date:     s.RecordDate,
+				Message:  fmt.Sprintf("Negative propane volume of %.2f litres", s.Litres),
+				Rule:     r.Name(),
+				Severity: r.Severity,
+				TankID:   s.TankID,
+				Value:    s.Litres,
+			})
+		}
+	}
+	return findings
+}
+
+// ==================== ZeroSales ==================== //
+
+// ZeroSales rule flags station days or tank days without any litres sold
+// gap placeholders are skipped as they are already reported as gaps
+type ZeroSales struct {
+	Severity model.Severity
+}
+
+// Name method
+func (r *ZeroSales) Name() string { return "zero_sales" }
+
+// CheckFuel method
+func (r *ZeroSales) CheckFuel(sales []*model.FuelSalesExport) (findings []*model.Finding) {
+	for _, s := range sales {
+		if s.Placeholder {
+			continue
+		}
+		var total float64
+		for _, grade := range model.FuelGrades {
+			total += s.FuelSales.Grade(grade)
+		}
+		if total == 0 {
+			findings = append(findings, &model.Finding{
+				Date:      s.RecordDate,
+				Message:   "No fuel sold",
+				Rule:      r.Name(),
+				Severity:  r.Severity,
+				StationID: s.StationID.Hex(),
+			})
+		}
+	}
+	return findings
+}
+
+// CheckPropane method
+func (r *ZeroSales) CheckPropane(sales []*model.PropaneSaleExport) (findings []*model.Finding) {
+	for _, s := range sales {
+		if s.Litres == 0 {
+			findings = append(findings, &model.Finding{
+				Date:     s.RecordDate,
+				Message:  "No propane sold",
+				Rule:     r.Name(),
+				Severity: r.Severity,
+				TankID:   s.TankID,
+			})
+		}
+	}
+	return findings
+}
+
+// ==================== CostRange ==================== //
+
+// CostRange rule flags average costs outside the accepted range for a grade
+// grades without a cost are skipped
+type CostRange struct {
+	Severity model.Severity
+	Ranges   map[string]CostBounds
+}
+
+// Name method
+func (r *CostRange) Name() string { return "cost_range" }
+
+// CheckFuel method
+func (r *CostRange) CheckFuel(sales []*model.FuelSalesExport) (findings []*model.Finding) {
+	for _, s := range sales {
+		for _, grade := range model.FuelGrades {
+			bounds, ok := r.Ranges[grade]
+			cost := s.AvgFuelCosts[grade]
+			if !ok || cost == 0 {
+				continue
+			}
+			if cost < bounds.Min || cost > bounds.Max {
+				findings = append(findings, &model.Finding{
+					Date:      s.RecordDate,
+					Grade:     grade,
+					Message:   fmt.Sprintf("%s cost of %.4f is outside %.2f - %.2f", grade, cost, bounds.Min, bounds.Max),
+					Rule:      r.Name(),
+					Severity:  r.Severity,
+					StationID: s.StationID.Hex(),
+					Value:     cost,
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// CheckPropane method
+func (r *CostRange) CheckPropane(sales []*model.PropaneSaleExport) []*model.Finding {
+	return nil
+}
+
+// ==================== VolumeSpike ==================== //
+
+// VolumeSpike rule flags days where the litres sold are more than StdDevs
+// standard deviations from the mean of the preceding days, up to Window days
+// (defaultSpikeWindow when zero). Days with fewer than MinDays preceding values are skipped.
+// The change must also be more than MinChange of the mean (defaultSpikeMinChange when zero),
+// so a flat or near flat window does not flag every small change.
+// gap placeholders are left out of the series as they are already reported as gaps
+type VolumeSpike struct {
+	Severity  model.Severity
+	StdDevs   float64
+	MinDays   int
+	Window    int
+	MinChange float64
+}
+
+const (
+	defaultSpikeWindow    = 7
+	defaultSpikeMinChange = 0.25
+)
+
+type point struct {
+	date  int
+	value float64
+}
+
+// Name method
+func (r *VolumeSpike) Name() string { return "volume_spike" }
+
+// CheckFuel method
+func (r *VolumeSpike) CheckFuel(sales []*model.FuelSalesExport) (findings []*model.Finding) {
+
+	series := make(map[string][]point)
+	for _, s := range sales {
+		if s.Placeholder {
+			continue
+		}
+		for _, grade := range model.FuelGrades {
+			key := s.StationID.Hex() + "|" + grade
+			series[key] = append(series[key], point{date: s.RecordDate, value: s.FuelSales.Grade(grade)})
+		}
+	}
+
+	for _, key := range sortedKeys(series) {
+		stationID, grade := splitKey(key)
+		for _, p := range r.spikes(series[key]) {
+			findings = append(findings, &model.Finding{
+				Date:      p.date,
+				Grade:     grade,
+				Message:   fmt.Sprintf("%s volume of %.2f litres is more than %.1f standard deviations from the preceding days", grade, p.value, r.StdDevs),
+				Rule:      r.Name(),
+				Severity:  r.Severity,
+				StationID: stationID,
+				Value:     p.value,
+			})
+		}
+	}
+	return findings
+}
+
+// CheckPropane method
+func (r *VolumeSpike) CheckPropane(sales []*model.PropaneSaleExport) (findings []*model.Finding) {
+
+	series := make(map[int][]point)
+	var tanks []int
+	for _, s := range sales {
+		if _, ok := series[s.TankID]; !ok {
+			tanks = append(tanks, s.TankID)
+		}
+		series[s.TankID] = append(series[s.TankID], point{date: s.RecordDate, value: s.Litres})
+	}
+	sort.Ints(tanks)
+
+	for _, tank := range tanks {
+		for _, p := range r.spikes(series[tank]) {
+			findings = append(findings, &model.Finding{
+				Date:     p.date,
+				Message:  fmt.Sprintf("Propane volume of %.2f litres is more than %.1f standard deviations from the preceding days", p.value, r.StdDevs),
+				Rule:     r.Name(),
+				Severity: r.Severity,
+				TankID:   tank,
+				Value:    p.value,
+			})
+		}
+	}
+	return findings
+}
+
+// spikes method compares each point, in date order, with the sample mean and standard deviation
+// of the trailing window before it
+func (r *VolumeSpike) spikes(pts []point) (res []point) {
+
+	window := r.Window
+	if window <= 0 {
+		window = defaultSpikeWindow
+	}
+	minChange := r.MinChange
+	if minChange <= 0 {
+		minChange = defaultSpikeMinChange
+	}
+
+	pts = append([]point(nil), pts...)
+	sort.SliceStable(pts, func(i, j int) bool { return pts[i].date < pts[j].date })
+
+	for i, p := range pts {
+		start := i - window
+		if start < 0 {
+			start = 0
+		}
+		prev := pts[start:i]
+		if len(prev) < r.MinDays || len(prev) < 2 {
+			continue
+		}
+
+		mean, stdDev := meanStdDev(prev)
+		dev := math.Abs(p.value - mean)
+		if dev > r.StdDevs*stdDev && dev > minChange*math.Abs(mean) {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+// meanStdDev function returns the mean and sample standard deviation of the values
+func meanStdDev(pts []point) (mean, stdDev float64) {
+
+	for _, p := range pts {
+		mean += p.value
+	}
+	mean /= float64(len(pts))
+
+	var sq float64
+	for _, p := range pts {
+		sq += (p.value - mean) * (p.value - mean)
+	}
+	return mean, math.Sqrt(sq / float64(len(pts)-1))
+}
+
+func sortedKeys(m map[string][]point) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func splitKey(key string) (stationID, grade string) {
+	parts := strings.SplitN(key, "|", 2)
+	return parts[0], parts[1]
+}
@@ -0,0 +1,86 @@
+package render
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/pulpfree/gsales-fs-export/model"
+)
+
+// CSV function writes the export data as RFC 4180 csv
+func CSV(w io.Writer, data *model.ExportData) error {
+
+	switch data.ExportType {
+	case model.FuelType:
+		return FuelCSV(w, data.Fuel, data.Stations)
+	case model.PropaneType:
+		return PropaneCSV(w, data.Propane)
+	}
+	return fmt.Errorf("Invalid export type for csv: %s", data.ExportType)
+}
+
+// FuelCSV function writes a row per station and day
+func FuelCSV(w io.Writer, sales []*model.FuelSalesExport, stations map[string]string) error {
+
+	cw := newWriter(w)
+	header := append([]string{"Date", "Station"}, model.FuelGrades...)
+	header = append(header, "AvgFuelCost")
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, s := range sales {
+		row := []string{formatDate(s.RecordDate), stations[s.StationID.Hex()]}
+		for _, grade := range model.FuelGrades {
+			row = append(row, formatFloat(s.FuelSales.Grade(grade), 3))
+		}
+		row = append(row, formatFloat(s.AvgFuelCost, 4))
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// PropaneCSV function writes a row per tank and day
+func PropaneCSV(w io.Writer, sales []*model.PropaneSaleExport) error {
+
+	cw := newWriter(w)
+	if err := cw.Write([]string{"Date", "Tank", "Litres"}); err != nil {
+		return err
+	}
+
+	for _, s := range sales {
+		row := []string{formatDate(s.RecordDate), strconv.Itoa(s.TankID), formatFloat(s.Litres, 3)}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// newWriter returns a csv writer using CRLF line endings as set out in RFC 4180
+func newWriter(w io.Writer) *csv.Writer {
+	cw := csv.NewWriter(w)
+	cw.UseCRLF = true
+	return cw
+}
+
+// formatDate converts a YYYYMMDD integer to YYYY-MM-DD
+func formatDate(date int) string {
+	d := strconv.Itoa(date)
+	if len(d) != 8 {
+		return d
+	}
+	return d[0:4] + "-" + d[4:6] + "-" + d[6:8]
+}
+
+func formatFloat(f float64, prec int) string {
+	return strconv.FormatFloat(f, 'f', prec, 64)
+}
@@ -0,0 +1,47 @@
+package render
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/pulpfree/gsales-fs-export/model"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestFuelCSV function
+func TestFuelCSV(t *testing.T) {
+
+	stationID, _ := primitive.ObjectIDFromHex("56cf1815982d82b0f3000001")
+	data := &model.ExportData{
+		ExportType: model.FuelType,
+		Fuel: []*model.FuelSalesExport{{
+			AvgFuelCost: 1.1234,
+			FuelSales:   &model.FuelSales{NL: 1000.5, SNL: 200, DSL: 300, CDSL: 0, PROP: 12.25},
+			RecordDate:  20230601,
+			StationID:   stationID,
+		}},
+		Stations: map[string]string{stationID.Hex(): "Bridge, Main St"},
+	}
+
+	var buf bytes.Buffer
+	err := CSV(&buf, data)
+	assert.NoError(t, err)
+	expect := "Date,Station,NL,SNL,DSL,CDSL,PROP,AvgFuelCost\r\n" +
+		"2023-06-01,\"Bridge, Main St\",1000.500,200.000,300.000,0.000,12.250,1.1234\r\n"
+	assert.Equal(t, expect, buf.String())
+}
+
+// TestPropaneCSV function
+func TestPropaneCSV(t *testing.T) {
+
+	data := &model.ExportData{
+		ExportType: model.PropaneType,
+		Propane:    []*model.PropaneSaleExport{{RecordDate: 20230601, TankID: 475, Litres: 88.5}},
+	}
+
+	var buf bytes.Buffer
+	err := CSV(&buf, data)
+	assert.NoError(t, err)
+	assert.Equal(t, "Date,Tank,Litres\r\n2023-06-01,475,88.500\r\n", buf.String())
+}
@@ -0,0 +1,172 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"sort"
+	"text/template"
+
+	"github.com/pulpfree/gsales-fs-export/model"
+)
+
+// Summary struct is a rendered export summary email
+type Summary struct {
+	HTML    string
+	Subject string
+	Text    string
+}
+
+// SummaryRow struct holds the litres per grade for a station, or the total across stations
+// Previous is the total for the prior period, used for the period over period change
+type SummaryRow struct {
+	Litres   []float64
+	Name     string
+	Previous float64
+	Total    float64
+}
+
+// Change method returns the period over period change as a percentage, or n/a without a prior period
+func (r *SummaryRow) Change() string {
+	if r.Previous == 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%+.1f%%", (r.Total-r.Previous)/r.Previous*100)
+}
+
+// SummaryView struct is the data passed to the summary templates
+type SummaryView struct {
+	DateEnd        string
+	DateStart      string
+	Grades         []string
+	RecordQuantity int
+	Rows           []*SummaryRow
+	Total          *SummaryRow
+	Warnings       []string
+}
+
+// FuelSummary function renders the html and text summary of a fuel export
+// prev holds the sales for the period of the same length before the export, it may be nil
+func FuelSummary(data, prev *model.ExportData, res *model.DnImportRes) (*Summary, error) {
+
+	view := fuelSummaryView(data, prev, res)
+
+	var html, text bytes.Buffer
+	if err := summaryHTML.Execute(&html, view); err != nil {
+		return nil, err
+	}
+	if err := summaryText.Execute(&text, view); err != nil {
+		return nil, err
+	}
+
+	return &Summary{
+		HTML:    html.String(),
+		Subject: fmt.Sprintf("Fuel sales export %s to %s", view.DateStart, view.DateEnd),
+		Text:    text.String(),
+	}, nil
+}
+
+func fuelSummaryView(data, prev *model.ExportData, res *model.DnImportRes) *SummaryView {
+
+	view := &SummaryView{
+		DateEnd:        res.DateEnd,
+		DateStart:      res.DateStart,
+		Grades:         model.FuelGrades,
+		RecordQuantity: res.RecordQuantity,
+		Total:          &SummaryRow{Litres: make([]float64, len(model.FuelGrades)), Name: "Total"},
+	}
+
+	rows := make(map[string]*SummaryRow)
+	row := func(id string) *SummaryRow {
+		r, ok := rows[id]
+		if !ok {
+			r = &SummaryRow{Litres: make([]float64, len(model.FuelGrades)), Name: stationName(data.Stations, id)}
+			rows[id] = r
+		}
+		return r
+	}
+
+	for _, s := range data.Fuel {
+		r := row(s.StationID.Hex())
+		for i, g := range model.FuelGrades {
+			v := s.FuelSales.Grade(g)
+			r.Litres[i] += v
+			r.Total += v
+			view.Total.Litres[i] += v
+			view.Total.Total += v
+		}
+	}
+
+	// prior period sales only count towards stations in the current export
+	if prev != nil {
+		for _, s := range prev.Fuel {
+			r, ok := rows[s.StationID.Hex()]
+			if !ok {
+				continue
+			}
+			for _, g := range model.FuelGrades {
+				v := s.FuelSales.Grade(g)
+				r.Previous += v
+				view.Total.Previous += v
+			}
+		}
+	}
+
+	for _, r := range rows {
+		view.Rows = append(view.Rows, r)
+	}
+	sort.Slice(view.Rows, func(i, j int) bool { return view.Rows[i].Name < view.Rows[j].Name })
+
+	for _, f := range res.Findings {
+		if f.Severity != model.SeverityWarn {
+			continue
+		}
+		w := fmt.Sprintf("%s: %s", formatDate(f.Date), f.Message)
+		if f.StationID != "" {
+			w = fmt.Sprintf("%s %s: %s", formatDate(f.Date), stationName(data.Stations, f.StationID), f.Message)
+		}
+		view.Warnings = append(view.Warnings, w)
+	}
+	if len(res.Gaps) > 0 {
+		view.Warnings = append(view.Warnings, fmt.Sprintf("%d station days without sales", len(res.Gaps)))
+	}
+
+	return view
+}
+
+func litres(f float64) string {
+	return formatFloat(f, 1)
+}
+
+var summaryHTML = htmltemplate.Must(htmltemplate.New("summary").Funcs(htmltemplate.FuncMap{"litres": litres}).Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: Arial, sans-serif; font-size: 14px;">
+<h2>Fuel sales export {{.DateStart}} to {{.DateEnd}}</h2>
+<p>{{.RecordQuantity}} records exported.</p>
+<table cellpadding="4" cellspacing="0" border="1" style="border-collapse: collapse;">
+<tr><th align="left">Station</th>{{range .Grades}}<th align="right">{{.}}</th>{{end}}<th align="right">Total</th><th align="right">Prior Period</th><th align="right">Change</th></tr>
+{{range .Rows}}<tr><td>{{.Name}}</td>{{range .Litres}}<td align="right">{{litres .}}</td>{{end}}<td align="right">{{litres .Total}}</td><td align="right">{{litres .Previous}}</td><td align="right">{{.Change}}</td></tr>
+{{end}}{{with .Total}}<tr style="font-weight: bold;"><td>{{.Name}}</td>{{range .Litres}}<td align="right">{{litres .}}</td>{{end}}<td align="right">{{litres .Total}}</td><td align="right">{{litres .Previous}}</td><td align="right">{{.Change}}</td></tr>{{end}}
+</table>
+{{if .Warnings}}<h3>Warnings</h3>
+<ul>
+{{range .Warnings}}<li>{{.}}</li>
+{{end}}</ul>
+{{end}}</body>
+</html>
+`))
+
+var summaryText = template.Must(template.New("summary").Funcs(template.FuncMap{"litres": litres}).Parse(`Fuel sales export {{.DateStart}} to {{.DateEnd}}
+{{.RecordQuantity}} records exported.
+{{range $r := .Rows}}
+{{$r.Name}}
+{{range $i, $g := $.Grades}}  {{printf "%-6s" $g}}{{printf "%14s" (litres (index $r.Litres $i))}}
+{{end}}  Total {{printf "%14s" (litres $r.Total)}}  prior period {{litres $r.Previous}}, change {{$r.Change}}
+{{end}}{{with $r := .Total}}
+{{$r.Name}}
+{{range $i, $g := $.Grades}}  {{printf "%-6s" $g}}{{printf "%14s" (litres (index $r.Litres $i))}}
+{{end}}  Total {{printf "%14s" (litres $r.Total)}}  prior period {{litres $r.Previous}}, change {{$r.Change}}
+{{end}}{{if .Warnings}}
+Warnings
+{{range .Warnings}}  - {{.}}
+{{end}}{{end}}`))
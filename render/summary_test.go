@@ -0,0 +1,51 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/pulpfree/gsales-fs-export/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestFuelSummary function
+func TestFuelSummary(t *testing.T) {
+
+	bridge, _ := primitive.ObjectIDFromHex("56cf1815982d82b0f3000001")
+	main, _ := primitive.ObjectIDFromHex("56cf1815982d82b0f3000002")
+	data := &model.ExportData{
+		ExportType: model.FuelType,
+		Fuel: []*model.FuelSalesExport{
+			{FuelSales: &model.FuelSales{NL: 1000, DSL: 200}, RecordDate: 20230601, StationID: main},
+			{FuelSales: &model.FuelSales{NL: 500}, RecordDate: 20230601, StationID: bridge},
+			{FuelSales: &model.FuelSales{NL: 700}, RecordDate: 20230602, StationID: bridge},
+		},
+		Stations: map[string]string{bridge.Hex(): "Bridge & Main", main.Hex(): "Main St"},
+	}
+	prev := &model.ExportData{Fuel: []*model.FuelSalesExport{
+		{FuelSales: &model.FuelSales{NL: 1000}, StationID: bridge},
+	}}
+	res := &model.DnImportRes{
+		DateEnd:        "2023-06-07",
+		DateStart:      "2023-06-01",
+		Findings:       []*model.Finding{{Date: 20230602, Message: "Volume spike", Severity: model.SeverityWarn, StationID: bridge.Hex()}},
+		RecordQuantity: 3,
+	}
+
+	view := fuelSummaryView(data, prev, res)
+	require.Len(t, view.Rows, 2)
+	assert.Equal(t, "Bridge & Main", view.Rows[0].Name)
+	assert.Equal(t, 1200.0, view.Rows[0].Total)
+	assert.Equal(t, "+20.0%", view.Rows[0].Change())
+	assert.Equal(t, "n/a", view.Rows[1].Change())
+	assert.Equal(t, []float64{2200, 0, 200, 0, 0}, view.Total.Litres)
+	assert.Equal(t, []string{"2023-06-02 Bridge & Main: Volume spike"}, view.Warnings)
+
+	s, err := FuelSummary(data, prev, res)
+	require.NoError(t, err)
+	assert.Equal(t, "Fuel sales export 2023-06-01 to 2023-06-07", s.Subject)
+	assert.Contains(t, s.HTML, "<td>Bridge &amp; Main</td>")
+	assert.Contains(t, s.HTML, "<li>2023-06-02 Bridge &amp; Main: Volume spike</li>")
+	assert.Contains(t, s.Text, "  Total         1200.0  prior period 1000.0, change +20.0%")
+}
@@ -0,0 +1,161 @@
+package render
+
+import (
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/pulpfree/gsales-fs-export/model"
+)
+
+// XLSX function writes the export data as an excel workbook with a summary
+// sheet across all stations, a sheet per station and a propane sheet per tank.
+// Station and propane sheets have daily rows with subtotals for each YearWeek
+func XLSX(w io.Writer, data *model.ExportData) error {
+
+	wb := newWorkbook()
+
+	if len(data.Fuel) > 0 {
+		fuelSheets(wb, data.Fuel, data.Stations)
+	}
+	if len(data.Propane) > 0 {
+		propaneSheets(wb, data.Propane)
+	}
+	if len(wb.sheets) == 0 {
+		wb.addSheet("Summary").addRow(true, "No sales found")
+	}
+
+	return wb.write(w)
+}
+
+func fuelSheets(wb *workbook, sales []*model.FuelSalesExport, stations map[string]string) {
+
+	byStation := make(map[string][]*model.FuelSalesExport)
+	for _, s := range sales {
+		id := s.StationID.Hex()
+		byStation[id] = append(byStation[id], s)
+	}
+
+	// order stations by name, falling back to the id for unknown stations
+	ids := make([]string, 0, len(byStation))
+	for id := range byStation {
+		ids = append(ids, id)
+	}
+	name := func(id string) string { return stationName(stations, id) }
+	sort.Slice(ids, func(i, j int) bool { return name(ids[i]) < name(ids[j]) })
+
+	header := []interface{}{"Station"}
+	for _, g := range model.FuelGrades {
+		header = append(header, g)
+	}
+	header = append(header, "Total")
+
+	summary := wb.addSheet("Summary")
+	summary.addRow(true, header...)
+	grand := make(map[string]float64)
+
+	for _, id := range ids {
+		totals := stationSheet(wb.addSheet(name(id)), byStation[id])
+
+		vals := []interface{}{name(id)}
+		var all float64
+		for _, g := range model.FuelGrades {
+			vals = append(vals, totals[g])
+			all += totals[g]
+			grand[g] += totals[g]
+		}
+		summary.addRow(false, append(vals, all)...)
+	}
+
+	vals := []interface{}{"Total"}
+	var all float64
+	for _, g := range model.FuelGrades {
+		vals = append(vals, grand[g])
+		all += grand[g]
+	}
+	summary.addRow(true, append(vals, all)...)
+}
+
+// stationName returns the station name, falling back to the id for unknown stations
+func stationName(stations map[string]string, id string) string {
+	if nm, ok := stations[id]; ok && nm != "" {
+		return nm
+	}
+	return id
+}
+
+// stationSheet writes the daily rows with weekly subtotals, returning the station totals per grade
+func stationSheet(sh *sheet, sales []*model.FuelSalesExport) map[string]float64 {
+
+	sort.Slice(sales, func(i, j int) bool { return sales[i].RecordDate < sales[j].RecordDate })
+
+	header := []interface{}{"Date", "YearWeek"}
+	for _, g := range model.FuelGrades {
+		header = append(header, g)
+	}
+	sh.addRow(true, append(header, "AvgFuelCost")...)
+
+	totals := make(map[string]float64)
+	week := make(map[string]float64)
+	subtotal := func(yw int) {
+		vals := []interface{}{"Week " + strconv.Itoa(yw), yw}
+		for _, g := range model.FuelGrades {
+			vals = append(vals, week[g])
+		}
+		sh.addRow(true, vals...)
+		week = make(map[string]float64)
+	}
+
+	for i, s := range sales {
+		yw := model.YearWeek(s.RecordDate)
+		vals := []interface{}{formatDate(s.RecordDate), yw}
+		for _, g := range model.FuelGrades {
+			v := s.FuelSales.Grade(g)
+			vals = append(vals, v)
+			week[g] += v
+			totals[g] += v
+		}
+		sh.addRow(false, append(vals, s.AvgFuelCost)...)
+
+		if i == len(sales)-1 || model.YearWeek(sales[i+1].RecordDate) != yw {
+			subtotal(yw)
+		}
+	}
+
+	return totals
+}
+
+func propaneSheets(wb *workbook, sales []*model.PropaneSaleExport) {
+
+	byTank := make(map[int][]*model.PropaneSaleExport)
+	var tanks []int
+	for _, s := range sales {
+		if _, ok := byTank[s.TankID]; !ok {
+			tanks = append(tanks, s.TankID)
+		}
+		byTank[s.TankID] = append(byTank[s.TankID], s)
+	}
+	sort.Ints(tanks)
+
+	for _, tank := range tanks {
+		sh := wb.addSheet("Propane " + strconv.Itoa(tank))
+		sh.addRow(true, "Date", "YearWeek", "Litres")
+
+		rows := byTank[tank]
+		sort.Slice(rows, func(i, j int) bool { return rows[i].RecordDate < rows[j].RecordDate })
+
+		var week, total float64
+		for i, s := range rows {
+			yw := model.YearWeek(s.RecordDate)
+			sh.addRow(false, formatDate(s.RecordDate), yw, s.Litres)
+			week += s.Litres
+			total += s.Litres
+
+			if i == len(rows)-1 || model.YearWeek(rows[i+1].RecordDate) != yw {
+				sh.addRow(true, "Week "+strconv.Itoa(yw), yw, week)
+				week = 0
+			}
+		}
+		sh.addRow(true, "Total", "", total)
+	}
+}
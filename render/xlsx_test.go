@@ -0,0 +1,97 @@
+package render
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/pulpfree/gsales-fs-export/model"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func readZip(t *testing.T, b []byte) map[string]string {
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	assert.NoError(t, err)
+
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		assert.NoError(t, err)
+		content, err := ioutil.ReadAll(rc)
+		assert.NoError(t, err)
+		rc.Close()
+
+		// every part must be well formed xml
+		dec := xml.NewDecoder(bytes.NewReader(content))
+		for {
+			if _, err := dec.Token(); err != nil {
+				assert.Equal(t, "EOF", err.Error(), f.Name)
+				break
+			}
+		}
+		files[f.Name] = string(content)
+	}
+	return files
+}
+
+// TestXLSX function
+func TestXLSX(t *testing.T) {
+
+	st1, _ := primitive.ObjectIDFromHex("56cf1815982d82b0f3000001")
+	st2, _ := primitive.ObjectIDFromHex("56cf1815982d82b0f3000002")
+
+	// 2023-06-03 is a Saturday, 2023-06-04 a Sunday which starts the next week
+	var fuel []*model.FuelSalesExport
+	for _, d := range []int{20230603, 20230604, 20230605} {
+		fuel = append(fuel,
+			&model.FuelSalesExport{FuelSales: &model.FuelSales{NL: 100, DSL: 10}, RecordDate: d, StationID: st1},
+			&model.FuelSalesExport{FuelSales: &model.FuelSales{NL: 50}, RecordDate: d, StationID: st2},
+		)
+	}
+	data := &model.ExportData{
+		ExportType: model.FuelType,
+		Fuel:       fuel,
+		Propane: []*model.PropaneSaleExport{
+			{RecordDate: 20230603, TankID: 476, Litres: 20},
+			{RecordDate: 20230603, TankID: 475, Litres: 10},
+		},
+		Stations: map[string]string{st1.Hex(): "Thorold: Pine St", st2.Hex(): "Bridge & Main"},
+	}
+
+	var buf bytes.Buffer
+	err := XLSX(&buf, data)
+	assert.NoError(t, err)
+
+	files := readZip(t, buf.Bytes())
+	assert.Contains(t, files, "[Content_Types].xml")
+	assert.Contains(t, files, "xl/styles.xml")
+
+	wb := files["xl/workbook.xml"]
+	for _, nm := range []string{"Summary", "Bridge &amp; Main", "Thorold  Pine St", "Propane 475", "Propane 476"} {
+		assert.Contains(t, wb, `name="`+nm+`"`)
+	}
+	assert.True(t, strings.Index(wb, "Propane 475") < strings.Index(wb, "Propane 476"))
+
+	// summary totals: 300 + 150 NL
+	assert.Contains(t, files["xl/worksheets/sheet1.xml"], "<v>450</v>")
+
+	// Thorold sheet has two weekly subtotals
+	thorold := files["xl/worksheets/sheet3.xml"]
+	assert.Equal(t, 2, strings.Count(thorold, ">Week "))
+	assert.Contains(t, thorold, "<v>200</v>")
+}
+
+// TestAddSheetNames function
+func TestAddSheetNames(t *testing.T) {
+
+	wb := newWorkbook()
+	assert.Equal(t, "Summary", wb.addSheet("Summary").name)
+	assert.Equal(t, "summary (2)", wb.addSheet("summary").name)
+	assert.Equal(t, "Sheet", wb.addSheet("[]").name)
+	assert.Len(t, []rune(wb.addSheet(strings.Repeat("x", 40)).name), maxSheetName)
+	assert.Equal(t, "AA", colName(26))
+}
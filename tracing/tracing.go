@@ -0,0 +1,221 @@
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pulpfree/gsales-fs-export/model"
+)
+
+// Environment variables set by the lambda runtime
+const (
+	DaemonAddressEnv = "AWS_XRAY_DAEMON_ADDRESS"
+	TraceHeaderEnv   = "_X_AMZN_TRACE_ID"
+)
+
+const (
+	defaultDaemonAddress = "127.0.0.1:2000"
+	daemonHeader         = `{"format": "json", "version": 1}` + "\n"
+)
+
+// Tracer struct sends X-Ray subsegments to the daemon over udp
+// A nil Tracer, and the spans it returns, do nothing so callers can trace unconditionally
+type Tracer struct {
+	conn     net.Conn
+	mu       sync.Mutex
+	parentID string
+	traceID  string
+}
+
+// Span struct is an X-Ray subsegment
+type Span struct {
+	annotations map[string]interface{}
+	id          string
+	mu          sync.Mutex
+	name        string
+	parentID    string
+	start       time.Time
+	tracer      *Tracer
+}
+
+// FromEnv function returns a tracer for the current lambda invocation
+// Returns nil when the invocation has no trace header or is not sampled
+func FromEnv() (*Tracer, error) {
+
+	traceID, parentID, sampled := ParseHeader(os.Getenv(TraceHeaderEnv))
+	if traceID == "" || parentID == "" || !sampled {
+		return nil, nil
+	}
+
+	addr := os.Getenv(DaemonAddressEnv)
+	if addr == "" {
+		addr = defaultDaemonAddress
+	}
+	// the daemon address may list separate tcp and udp addresses
+	for _, part := range strings.Fields(addr) {
+		if strings.HasPrefix(part, "udp:") {
+			addr = strings.TrimPrefix(part, "udp:")
+		}
+	}
+
+	return New(addr, traceID, parentID)
+}
+
+// New function returns a tracer sending subsegments of the parent segment to the daemon at addr
+func New(addr, traceID, parentID string) (*Tracer, error) {
+
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &Tracer{conn: conn, parentID: parentID, traceID: traceID}, nil
+}
+
+// ParseHeader function extracts the trace and parent ids from an X-Amzn-Trace-Id header
+// e.g. Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1
+func ParseHeader(header string) (traceID, parentID string, sampled bool) {
+	for _, part := range strings.Split(header, ";") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "Root":
+			traceID = kv[1]
+		case "Parent":
+			parentID = kv[1]
+		case "Sampled":
+			sampled = kv[1] == "1"
+		}
+	}
+	return traceID, parentID, sampled
+}
+
+// Close method
+func (t *Tracer) Close() error {
+	if t == nil {
+		return nil
+	}
+	return t.conn.Close()
+}
+
+// Start method starts a span directly under the lambda segment
+func (t *Tracer) Start(name string) *Span {
+	if t == nil {
+		return nil
+	}
+	return t.newSpan(name, t.parentID)
+}
+
+// Start method starts a child span
+func (s *Span) Start(name string) *Span {
+	if s == nil {
+		return nil
+	}
+	return s.tracer.newSpan(name, s.id)
+}
+
+// Annotate method adds an indexed annotation, values should be strings, numbers or booleans
+func (s *Span) Annotate(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.annotations == nil {
+		s.annotations = make(map[string]interface{})
+	}
+	s.annotations[key] = value
+}
+
+// End method completes the span and sends it to the daemon, err marks the span as faulted
+func (s *Span) End(err error) {
+
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	doc := &subsegment{
+		Annotations: s.annotations,
+		EndTime:     epoch(time.Now()),
+		ID:          s.id,
+		Name:        s.name,
+		ParentID:    s.parentID,
+		StartTime:   epoch(s.start),
+		TraceID:     s.tracer.traceID,
+		Type:        "subsegment",
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		doc.Fault = true
+		doc.Cause = &cause{Exceptions: []exception{{ID: newID(), Message: err.Error(), Type: errType(err)}}}
+	}
+	s.tracer.send(doc)
+}
+
+func (t *Tracer) newSpan(name, parentID string) *Span {
+	return &Span{id: newID(), name: name, parentID: parentID, start: time.Now(), tracer: t}
+}
+
+// send method writes the subsegment, tracing is best effort so errors are dropped
+func (t *Tracer) send(doc *subsegment) {
+	body, err := json.Marshal(doc)
+	if err != nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.conn.Write(append([]byte(daemonHeader), body...))
+}
+
+type subsegment struct {
+	Annotations map[string]interface{} `json:"annotations,omitempty"`
+	Cause       *cause                 `json:"cause,omitempty"`
+	EndTime     float64                `json:"end_time"`
+	Fault       bool                   `json:"fault,omitempty"`
+	ID          string                 `json:"id"`
+	Name        string                 `json:"name"`
+	ParentID    string                 `json:"parent_id"`
+	StartTime   float64                `json:"start_time"`
+	TraceID     string                 `json:"trace_id"`
+	Type        string                 `json:"type"`
+}
+
+type cause struct {
+	Exceptions []exception `json:"exceptions"`
+}
+
+type exception struct {
+	ID      string `json:"id"`
+	Message string `json:"message"`
+	Type    string `json:"type"`
+}
+
+// newID returns a random 64 bit id as 16 hex characters
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func epoch(t time.Time) float64 {
+	return float64(t.UnixNano()) / float64(time.Second)
+}
+
+// errType returns the code of a model.Error, falling back to the error's go type
+func errType(err error) string {
+	var mErr *model.Error
+	if errors.As(err, &mErr) {
+		return mErr.Code
+	}
+	return fmt.Sprintf("%T", err)
+}
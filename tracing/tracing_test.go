@@ -0,0 +1,98 @@
+package tracing
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pulpfree/gsales-fs-export/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readSubsegment reads a single daemon packet, returning the decoded subsegment
+func readSubsegment(t *testing.T, pc net.PacketConn) map[string]interface{} {
+	buf := make([]byte, 64*1024)
+	pc.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	require.NoError(t, err)
+
+	parts := strings.SplitN(string(buf[:n]), "\n", 2)
+	require.Len(t, parts, 2)
+	assert.Equal(t, strings.TrimSpace(daemonHeader), parts[0])
+
+	doc := make(map[string]interface{})
+	require.NoError(t, json.Unmarshal([]byte(parts[1]), &doc))
+	return doc
+}
+
+// TestParseHeader function
+func TestParseHeader(t *testing.T) {
+
+	traceID, parentID, sampled := ParseHeader("Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1")
+	assert.Equal(t, "1-5759e988-bd862e3fe1be46a994272793", traceID)
+	assert.Equal(t, "53995c3f42cd8ad8", parentID)
+	assert.True(t, sampled)
+
+	_, _, sampled = ParseHeader("Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=0")
+	assert.False(t, sampled)
+}
+
+// TestSpans function
+func TestSpans(t *testing.T) {
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer pc.Close()
+
+	tr, err := New(pc.LocalAddr().String(), "1-5759e988-bd862e3fe1be46a994272793", "53995c3f42cd8ad8")
+	require.NoError(t, err)
+	defer tr.Close()
+
+	root := tr.Start("export")
+	child := root.Start("compileStation")
+	child.Annotate("stationID", "56cf1815982d82b0f3000001")
+	child.End(&model.Error{Code: "mongo_timeout", Msg: "Mongo operation timed out"})
+
+	doc := readSubsegment(t, pc)
+	assert.Equal(t, "compileStation", doc["name"])
+	assert.Equal(t, "subsegment", doc["type"])
+	assert.Equal(t, root.id, doc["parent_id"])
+	assert.Equal(t, true, doc["fault"])
+	assert.Equal(t, "56cf1815982d82b0f3000001", doc["annotations"].(map[string]interface{})["stationID"])
+	exc := doc["cause"].(map[string]interface{})["exceptions"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "mongo_timeout", exc["type"])
+
+	root.End(nil)
+	doc = readSubsegment(t, pc)
+	assert.Equal(t, "export", doc["name"])
+	assert.Equal(t, "53995c3f42cd8ad8", doc["parent_id"])
+	assert.Equal(t, "1-5759e988-bd862e3fe1be46a994272793", doc["trace_id"])
+	assert.Nil(t, doc["fault"])
+	assert.True(t, doc["end_time"].(float64) >= doc["start_time"].(float64))
+}
+
+// TestNilTracer function
+func TestNilTracer(t *testing.T) {
+
+	var tr *Tracer
+	span := tr.Start("export")
+	span.Start("child").End(errors.New("ignored"))
+	span.Annotate("key", "value")
+	span.End(nil)
+	assert.NoError(t, tr.Close())
+}
+
+// TestFromEnvUnsampled function
+func TestFromEnvUnsampled(t *testing.T) {
+
+	os.Setenv(TraceHeaderEnv, "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=0")
+	defer os.Unsetenv(TraceHeaderEnv)
+	tr, err := FromEnv()
+	assert.NoError(t, err)
+	assert.Nil(t, tr)
+}
@@ -0,0 +1,58 @@
+package validators
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/pulpfree/gsales-fs-export/model"
+)
+
+// Validation error type constants
+const (
+	ErrDateOrder     = "date_order"
+	ErrFutureDate    = "future_date"
+	ErrInvalidDate   = "invalid_date"
+	ErrInvalidFormat = "invalid_format"
+	ErrInvalidGaps   = "invalid_gap_mode"
+	ErrInvalidType   = "invalid_export_type"
+	ErrMalformedBody = "malformed_body"
+	ErrMissingField  = "missing_field"
+	ErrRangeExceeded = "range_exceeded"
+	ErrUnknownField  = "unknown_field"
+)
+
+// ValidationError struct holds every violation found in a request
+type ValidationError struct {
+	Status     int
+	Violations []*model.ErrorResponse
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// add method appends a violation, a malformed request (400) takes precedence over
+// an unprocessable one (422)
+func (e *ValidationError) add(status int, tp, field, msg string) {
+	if e.Status != http.StatusBadRequest {
+		e.Status = status
+	}
+	e.Violations = append(e.Violations, &model.ErrorResponse{
+		Field:   field,
+		Message: msg,
+		Status:  status,
+		Type:    tp,
+	})
+}
+
+// errOrNil ensures we don't return a typed nil as an error
+func (e *ValidationError) errOrNil() error {
+	if len(e.Violations) == 0 {
+		return nil
+	}
+	return e
+}
@@ -1,7 +1,13 @@
 package validators
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/pulpfree/gsales-fs-export/model"
@@ -13,6 +19,12 @@ const (
 	timeRecordForm = "2006-01-02"
 )
 
+// maxRangeDays is the largest inclusive date span allowed per export type
+var maxRangeDays = map[model.ExportType]int{
+	model.FuelType:    62,
+	model.PropaneType: 366,
+}
+
 // Date function
 func Date(dateInput string) (time.Time, error) {
 
@@ -42,23 +54,171 @@ func Fuel(exportInput string) (model.ExportType, error) {
 	}
 }
 
-// RequestVars function
+// Gaps function validates the missing day handling option, defaulting to report
+func Gaps(gapsInput string) (model.GapMode, error) {
+	switch gapsInput {
+	case "", "report":
+		return model.GapReport, nil
+	case "fail":
+		return model.GapFail, nil
+	case "fill":
+		return model.GapFill, nil
+	default:
+		return "", errors.New("Invalid gaps option provided, must be one of report, fail or fill")
+	}
+}
+
+// Format function validates the download format, defaulting to json
+func Format(formatInput string) (model.Format, error) {
+	switch formatInput {
+	case "", "json":
+		return model.FormatJSON, nil
+	case "csv":
+		return model.FormatCSV, nil
+	case "xlsx":
+		return model.FormatXLSX, nil
+	default:
+		return "", errors.New("Invalid format provided, must be one of json, csv or xlsx")
+	}
+}
+
+// DecodeRequest function parses the request body, rejecting malformed json and unknown fields
+func DecodeRequest(body string) (r *model.RequestInput, err error) {
+
+	vErr := &ValidationError{}
+	if strings.TrimSpace(body) == "" {
+		vErr.add(http.StatusBadRequest, ErrMalformedBody, "", "Request body is empty")
+		return nil, vErr
+	}
+
+	dec := json.NewDecoder(bytes.NewReader([]byte(body)))
+	dec.DisallowUnknownFields()
+
+	r = &model.RequestInput{}
+	if err = dec.Decode(r); err != nil {
+		if field, ok := unknownField(err); ok {
+			vErr.add(http.StatusBadRequest, ErrUnknownField, field, fmt.Sprintf("Unknown field: %s", field))
+		} else {
+			vErr.add(http.StatusBadRequest, ErrMalformedBody, "", fmt.Sprintf("Malformed request body: %s", err))
+		}
+		return nil, vErr
+	}
+	if dec.More() {
+		vErr.add(http.StatusBadRequest, ErrMalformedBody, "", "Malformed request body: unexpected data after json object")
+		return nil, vErr
+	}
+
+	return r, nil
+}
+
+// unknownFieldRe matches the decoder error for a field DisallowUnknownFields rejects,
+// encoding/json has no typed error for it, TestUnknownField pins the wording
+var unknownFieldRe = regexp.MustCompile(`^json: unknown field "(.+)"$`)
+
+// unknownField function returns the field named by an unknown field decode error
+// Errors in any other form are reported as a malformed body
+func unknownField(err error) (string, bool) {
+	m := unknownFieldRe.FindStringSubmatch(err.Error())
+	if m == nil {
+		return "", false
+	}
+	return m[1], true
+}
+
+// RequestVars function validates the request input, reporting all violations in a ValidationError
 func RequestVars(r *model.RequestInput) (res *model.Request, err error) {
 
+	vErr := &ValidationError{}
+	if r == nil {
+		vErr.add(http.StatusBadRequest, ErrMalformedBody, "", "Request body is empty")
+		return nil, vErr
+	}
+
 	res = new(model.Request)
-	res.ExportType, err = Fuel(r.ExportType)
-	if err != nil {
+	if r.ExportType == "" {
+		vErr.add(http.StatusUnprocessableEntity, ErrMissingField, "exportType", "Missing export type")
+	} else if res.ExportType, err = Fuel(r.ExportType); err != nil {
+		vErr.add(http.StatusUnprocessableEntity, ErrInvalidType, "exportType", err.Error())
+	}
+
+	if res.GapMode, err = Gaps(r.Gaps); err != nil {
+		vErr.add(http.StatusUnprocessableEntity, ErrInvalidGaps, "gaps", err.Error())
+	}
+
+	startOK := requestDate(vErr, "dateStart", r.DateStart, &res.DateStart)
+	endOK := requestDate(vErr, "dateEnd", r.DateEnd, &res.DateEnd)
+
+	if startOK && endOK {
+		if res.DateStart.After(res.DateEnd) {
+			vErr.add(http.StatusUnprocessableEntity, ErrDateOrder, "dateStart", "Invalid date range. dateStart must be on or before dateEnd")
+		} else if limit, ok := maxRangeDays[res.ExportType]; ok {
+			days := int(res.DateEnd.Sub(res.DateStart).Hours()/24) + 1
+			if days > limit {
+				vErr.add(http.StatusUnprocessableEntity, ErrRangeExceeded, "dateEnd", fmt.Sprintf("Invalid date range. %s export is limited to %d days, requested %d", res.ExportType, limit, days))
+			}
+		}
+	}
+
+	if err = vErr.errOrNil(); err != nil {
 		return res, err
 	}
 
-	res.DateStart, err = Date(r.DateStart)
+	return res, nil
+}
+
+// requestDate validates a single date field, returning true when valid
+func requestDate(vErr *ValidationError, field, input string, dte *time.Time) bool {
+
+	if input == "" {
+		vErr.add(http.StatusUnprocessableEntity, ErrMissingField, field, fmt.Sprintf("Missing %s", field))
+		return false
+	}
+
+	date, err := time.Parse(timeRecordForm, input)
 	if err != nil {
-		return res, err
+		vErr.add(http.StatusUnprocessableEntity, ErrInvalidDate, field, fmt.Sprintf("Invalid %s. Expected format YYYY-MM-DD", field))
+		return false
+	}
+	if _, err = Date(input); err != nil {
+		vErr.add(http.StatusUnprocessableEntity, ErrFutureDate, field, err.Error())
+		return false
 	}
-	res.DateEnd, err = Date(r.DateEnd)
+	*dte = date
+
+	return true
+}
+
+// DataRequest function validates the query parameters of a data download request
+func DataRequest(params map[string]string) (res *model.Request, format model.Format, err error) {
+
+	vErr := &ValidationError{}
+	for key := range params {
+		switch key {
+		case "exportType", "dateStart", "dateEnd", "format":
+		default:
+			vErr.add(http.StatusBadRequest, ErrUnknownField, key, fmt.Sprintf("Unknown field: %s", key))
+		}
+	}
+	if err = vErr.errOrNil(); err != nil {
+		return nil, format, err
+	}
+
+	res, err = RequestVars(&model.RequestInput{
+		DateEnd:    params["dateEnd"],
+		DateStart:  params["dateStart"],
+		ExportType: params["exportType"],
+	})
 	if err != nil {
-		return res, err
+		errors.As(err, &vErr)
 	}
 
-	return res, nil
+	if format, err = Format(params["format"]); err != nil {
+		vErr.add(http.StatusUnprocessableEntity, ErrInvalidFormat, "format", err.Error())
+	}
+
+	if err = vErr.errOrNil(); err != nil {
+		return res, format, err
+	}
+
+	return res, format, nil
 }
@@ -1,6 +1,10 @@
 package validators
 
 import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
 	"testing"
 	"time"
 
@@ -68,3 +72,155 @@ func TestValidRequest(t *testing.T) {
 	assert.NoError(t, err)
 	assert.IsType(t, reqTp, res)
 }
+
+// TestInvalidRequestReportsAll function
+func TestInvalidRequestReportsAll(t *testing.T) {
+
+	testVars := &model.RequestInput{
+		DateStart:  "2018-0101",
+		ExportType: "diesel",
+	}
+
+	_, err := RequestVars(testVars)
+
+	var vErr *ValidationError
+	assert.True(t, errors.As(err, &vErr))
+	assert.Equal(t, http.StatusUnprocessableEntity, vErr.Status)
+	assert.Len(t, vErr.Violations, 3)
+	assert.Equal(t, ErrInvalidType, vErr.Violations[0].Type)
+	assert.Equal(t, ErrInvalidDate, vErr.Violations[1].Type)
+	assert.Equal(t, ErrMissingField, vErr.Violations[2].Type)
+	assert.Equal(t, "dateEnd", vErr.Violations[2].Field)
+}
+
+// TestInvalidDateOrder function
+func TestInvalidDateOrder(t *testing.T) {
+
+	testVars := &model.RequestInput{
+		DateStart:  "2018-02-28",
+		DateEnd:    "2018-01-01",
+		ExportType: "fuel",
+	}
+
+	_, err := RequestVars(testVars)
+
+	var vErr *ValidationError
+	assert.True(t, errors.As(err, &vErr))
+	assert.Len(t, vErr.Violations, 1)
+	assert.Equal(t, ErrDateOrder, vErr.Violations[0].Type)
+}
+
+// TestInvalidDateRange function
+func TestInvalidDateRange(t *testing.T) {
+
+	testVars := &model.RequestInput{
+		DateStart:  "2018-01-01",
+		DateEnd:    "2018-06-30",
+		ExportType: "fuel",
+	}
+
+	_, err := RequestVars(testVars)
+
+	var vErr *ValidationError
+	assert.True(t, errors.As(err, &vErr))
+	assert.Equal(t, ErrRangeExceeded, vErr.Violations[0].Type)
+
+	// Propane allows a longer range
+	testVars.ExportType = "propane"
+	_, err = RequestVars(testVars)
+	assert.NoError(t, err)
+}
+
+// TestDecodeRequest function
+func TestDecodeRequest(t *testing.T) {
+
+	r, err := DecodeRequest(`{"exportType": "fuel", "dateStart": "2018-01-01", "dateEnd": "2018-01-31"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, "fuel", r.ExportType)
+
+	var vErr *ValidationError
+
+	_, err = DecodeRequest(`{"exportType": "fuel", "dateFrom": "2018-01-01"}`)
+	assert.True(t, errors.As(err, &vErr))
+	assert.Equal(t, http.StatusBadRequest, vErr.Status)
+	assert.Equal(t, ErrUnknownField, vErr.Violations[0].Type)
+	assert.Equal(t, "dateFrom", vErr.Violations[0].Field)
+
+	_, err = DecodeRequest(`{"exportType": `)
+	assert.True(t, errors.As(err, &vErr))
+	assert.Equal(t, ErrMalformedBody, vErr.Violations[0].Type)
+
+	_, err = DecodeRequest("")
+	assert.True(t, errors.As(err, &vErr))
+	assert.Equal(t, ErrMalformedBody, vErr.Violations[0].Type)
+}
+
+// TestUnknownField function pins the encoding/json wording unknownField parses, so a change
+// in a Go release fails here rather than reporting unknown fields as malformed bodies
+func TestUnknownField(t *testing.T) {
+
+	dec := json.NewDecoder(strings.NewReader(`{"dateFrom": "2018-01-01"}`))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(&model.RequestInput{})
+	assert.EqualError(t, err, `json: unknown field "dateFrom"`)
+
+	field, ok := unknownField(err)
+	assert.True(t, ok)
+	assert.Equal(t, "dateFrom", field)
+
+	_, ok = unknownField(errors.New("json: cannot unmarshal number into Go struct field"))
+	assert.False(t, ok)
+}
+
+// TestGapsOption function
+func TestGapsOption(t *testing.T) {
+
+	testVars := &model.RequestInput{
+		DateStart:  "2018-01-01",
+		DateEnd:    "2018-01-31",
+		ExportType: "fuel",
+	}
+
+	res, err := RequestVars(testVars)
+	assert.NoError(t, err)
+	assert.Equal(t, model.GapReport, res.GapMode)
+
+	testVars.Gaps = "fill"
+	res, err = RequestVars(testVars)
+	assert.NoError(t, err)
+	assert.Equal(t, model.GapFill, res.GapMode)
+
+	testVars.Gaps = "ignore"
+	_, err = RequestVars(testVars)
+	var vErr *ValidationError
+	assert.True(t, errors.As(err, &vErr))
+	assert.Equal(t, ErrInvalidGaps, vErr.Violations[0].Type)
+}
+
+// TestDataRequest function
+func TestDataRequest(t *testing.T) {
+
+	params := map[string]string{
+		"dateStart":  "2018-01-01",
+		"dateEnd":    "2018-01-31",
+		"exportType": "propane",
+		"format":     "csv",
+	}
+	res, format, err := DataRequest(params)
+	assert.NoError(t, err)
+	assert.Equal(t, model.PropaneType, res.ExportType)
+	assert.Equal(t, model.FormatCSV, format)
+
+	params["format"] = "pdf"
+	params["dateEnd"] = ""
+	_, _, err = DataRequest(params)
+	var vErr *ValidationError
+	assert.True(t, errors.As(err, &vErr))
+	assert.Len(t, vErr.Violations, 2)
+	assert.Equal(t, ErrInvalidFormat, vErr.Violations[1].Type)
+
+	params["station"] = "1"
+	_, _, err = DataRequest(params)
+	assert.True(t, errors.As(err, &vErr))
+	assert.Equal(t, http.StatusBadRequest, vErr.Status)
+}